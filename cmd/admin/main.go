@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"spa_media_review/config"
+	"spa_media_review/database"
+)
+
+const usage = `Usage: admin <command>
+
+Commands:
+  migrate-covers    move base64 book covers into the blob store
+  purge-trash       permanently remove trash older than TRASH_RETENTION_DAYS
+  recompute-ratings rebuild every book's rating aggregates from its reviews
+  strip-review-copies
+                    remove embedded user and book copies from old reviews
+  reconcile         repair stale book and username snapshots on reviews and
+                    comments
+  dedupe-reviews    keep one review per user and book, then recompute ratings
+  normalize-emails  lower-case stored email addresses and list accounts that
+                    share one`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	config.LoadEnv()
+
+	if err := database.Connect_to_mongodb(); err != nil {
+		log.Fatal("Could not connect to MongoDB:", err)
+	}
+	defer database.DisconnectDB()
+
+	if err := run(context.Background(), os.Args[1]); err != nil {
+		log.Printf("%s failed: %v", os.Args[1], err)
+		database.DisconnectDB()
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, command string) error {
+	switch command {
+	case "migrate-covers":
+		blobStore, err := config.SetupBlobStore(database.DB)
+		if err != nil {
+			return err
+		}
+		migrated, err := database.MigrateBookImages(ctx, database.BookCollection, blobStore)
+		fmt.Printf("Migrated %d book covers\n", migrated)
+		return err
+	case "purge-trash":
+		blobStore, err := config.SetupBlobStore(database.DB)
+		if err != nil {
+			return err
+		}
+		cutoff := time.Now().Add(-config.TrashRetention())
+		books, reviews, err := database.PurgeTrash(ctx, database.Trash(), blobStore, cutoff)
+		fmt.Printf("Purged %d books and %d reviews\n", books, reviews)
+		return err
+	case "recompute-ratings":
+		updated, err := database.RecomputeRatings(ctx, database.BookCollection, database.ReviewCollection)
+		fmt.Printf("Recomputed ratings for %d books\n", updated)
+		return err
+	case "strip-review-copies":
+		stripped, err := database.StripReviewCopies(ctx, database.ReviewCollection)
+		fmt.Printf("Stripped embedded copies from %d reviews\n", stripped)
+		return err
+	case "reconcile":
+		report, err := database.ReconcileSnapshots(ctx, database.BookCollection, database.UserCollection, database.ReviewCollection, database.CommentCollection)
+		fmt.Printf("Fixed %d book snapshots, %d usernames on reviews and %d usernames on comments\n", report.BookSnapshots, report.Usernames, report.CommentUsernames)
+		return err
+	case "dedupe-reviews":
+		removed, err := database.DedupeReviews(ctx, database.ReviewCollection)
+		fmt.Printf("Removed %d duplicate reviews\n", removed)
+		if err != nil {
+			return err
+		}
+		updated, err := database.RecomputeRatings(ctx, database.BookCollection, database.ReviewCollection)
+		fmt.Printf("Recomputed ratings for %d books\n", updated)
+		return err
+	case "normalize-emails":
+		normalized, conflicts, err := database.NormalizeEmails(ctx, database.UserCollection)
+		fmt.Printf("Normalized %d email addresses\n", normalized)
+		if err != nil {
+			return err
+		}
+		for _, conflict := range conflicts {
+			ids := make([]string, len(conflict.UserIDs))
+			for i, id := range conflict.UserIDs {
+				ids[i] = id.Hex()
+			}
+			fmt.Printf("%s is shared by users %s\n", conflict.Email, strings.Join(ids, ", "))
+		}
+		if len(conflicts) > 0 {
+			return fmt.Errorf("%d email addresses belong to more than one account; merge or remove those accounts and run this again", len(conflicts))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+}
@@ -1,11 +1,20 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"spa_media_review/contentfilter"
 	"spa_media_review/controllers"
+	"spa_media_review/mailer"
 	"spa_media_review/middleware"
+	"spa_media_review/models"
+	"spa_media_review/revocation"
 	"spa_media_review/routes"
+	"spa_media_review/storage"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -36,14 +45,157 @@ func SetupServer() *gin.Engine {
 	return router
 }
 
-func SetupHandlers(router *gin.Engine, bookCollection *mongo.Collection, reviewCollection *mongo.Collection, userCollection *mongo.Collection) {
+func SetupBlobStore(db *mongo.Database) (storage.BlobStore, error) {
+	switch backend := GetEnv("BLOB_STORE", "local"); backend {
+	case "local":
+		return storage.NewLocalStore(GetEnv("BLOB_STORE_PATH", "uploads"))
+	case "gridfs":
+		return storage.NewGridFSStore(db, GetEnv("BLOB_STORE_BUCKET", "blobs"))
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE backend %q", backend)
+	}
+}
+
+// SetupContentFilter builds the filter chain that new and edited reviews
+// are run through. The wordlist is read from CONTENT_FILTER_WORDLIST, if
+// set; see contentfilter.ParseWordlist for its format.
+func SetupContentFilter(reviews *mongo.Collection) (contentfilter.Chain, error) {
+	var chain contentfilter.Chain
+
+	if path := os.Getenv("CONTENT_FILTER_WORDLIST"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open wordlist: %v", err)
+		}
+		defer file.Close()
+
+		wordlist, err := contentfilter.ParseWordlist(file)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, wordlist)
+	}
+
+	return append(chain,
+		contentfilter.LinkLimit{Max: envInt("CONTENT_FILTER_MAX_LINKS", 2, 0)},
+		contentfilter.DuplicateText{Reviews: reviews},
+		contentfilter.PostingRate{
+			Reviews: reviews,
+			Max:     int64(envInt("CONTENT_FILTER_RATE_LIMIT", 5, 1)),
+			Window:  time.Duration(envInt("CONTENT_FILTER_RATE_WINDOW_MINUTES", 60, 1)) * time.Minute,
+		},
+	), nil
+}
+
+// SetupMailer picks how email is delivered from MAILER: smtp relays it
+// through SMTP_HOST, log prints it and file saves it under MAILER_PATH.
+func SetupMailer() (mailer.Mailer, error) {
+	from := GetEnv("MAIL_FROM", "no-reply@localhost")
+	switch backend := GetEnv("MAILER", "log"); backend {
+	case "smtp":
+		return mailer.NewSMTPMailer(os.Getenv("SMTP_HOST"), envInt("SMTP_PORT", 587, 1), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+	case "log":
+		return mailer.NewLogMailer(from), nil
+	case "file":
+		return mailer.NewFileMailer(GetEnv("MAILER_PATH", "mail"), from)
+	default:
+		return nil, fmt.Errorf("unknown MAILER backend %q", backend)
+	}
+}
+
+// SetupRevocationStore picks where revoked tokens are remembered from
+// REVOCATION_STORE: mongo, the default, or memory, which forgets them on
+// restart and is only meant for tests.
+func SetupRevocationStore(db *mongo.Database) (revocation.Store, error) {
+	switch backend := GetEnv("REVOCATION_STORE", "mongo"); backend {
+	case "mongo":
+		return revocation.NewMongoStore(db.Collection("revoked_tokens")), nil
+	case "memory":
+		return revocation.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown REVOCATION_STORE backend %q", backend)
+	}
+}
+
+// CheckEmailVerification refuses REQUIRE_EMAIL_VERIFICATION without a
+// VERIFY_SECRET_KEY, since no verification link could be sent or checked.
+func CheckEmailVerification() error {
+	required, _ := strconv.ParseBool(os.Getenv("REQUIRE_EMAIL_VERIFICATION"))
+	if required && os.Getenv("VERIFY_SECRET_KEY") == "" {
+		return fmt.Errorf("REQUIRE_EMAIL_VERIFICATION is set but VERIFY_SECRET_KEY is not")
+	}
+	return nil
+}
+
+// SetupRatingDimensions configures the sub-ratings offered per book category
+// from RATING_DIMENSIONS, a semicolon-separated list of category=dimensions
+// entries such as "*=plot,characters,writing,pacing;Poetry=imagery,language".
+// The "*" entry applies to every category without one of its own.
+func SetupRatingDimensions() error {
+	value := os.Getenv("RATING_DIMENSIONS")
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	byCategory := map[string][]string{}
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		category, list, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(category) == "" {
+			return fmt.Errorf("invalid RATING_DIMENSIONS entry %q", entry)
+		}
+
+		var names []string
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		byCategory[category] = names
+	}
+	return models.SetRatingDimensions(byCategory)
+}
+
+// envInt reads an integer setting, falling back to def when the variable is
+// unset, malformed or below min.
+func envInt(key string, def, min int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		log.Printf("Invalid %s, using %d", key, def)
+		return def
+	}
+	return n
+}
+
+func TrashRetention() time.Duration {
+	days, err := strconv.Atoi(GetEnv("TRASH_RETENTION_DAYS", "30"))
+	if err != nil || days < 1 {
+		log.Printf("Invalid TRASH_RETENTION_DAYS, using 30 days")
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func SetupHandlers(router *gin.Engine, bookCollection *mongo.Collection, reviewCollection *mongo.Collection, userCollection *mongo.Collection, voteCollection *mongo.Collection, commentCollection *mongo.Collection, reportCollection *mongo.Collection, sessionCollection *mongo.Collection, blobStore storage.BlobStore, reviewFilter contentfilter.Chain, mail mailer.Mailer) {
 	homeController := controllers.NewHomeController(bookCollection, userCollection)
-	bookController := controllers.NewBookController(bookCollection, reviewCollection)
-	reviewController := controllers.NewReviewController(reviewCollection, bookCollection, userCollection)
-	userController := controllers.NewUserController(userCollection)
+	bookController := controllers.NewBookController(bookCollection, reviewCollection, blobStore)
+	reviewController := controllers.NewReviewController(reviewCollection, bookCollection, userCollection, voteCollection, commentCollection, reviewFilter)
+	commentController := controllers.NewCommentController(commentCollection, reviewCollection, userCollection)
+	userController := controllers.NewUserController(userCollection, reviewCollection, commentCollection, sessionCollection, mail)
+	adminController := controllers.NewAdminController(bookCollection, reviewCollection, userCollection, sessionCollection, TrashRetention())
+	moderationController := controllers.NewModerationController(reportCollection, reviewController)
 
 	routes.RegisterHomeRoute(router, homeController)
 	routes.RegisterBookRoutes(router, bookController)
 	routes.RegisterReviewRoutes(router, reviewController)
+	routes.RegisterCommentRoutes(router, commentController)
 	routes.RegisterUserRoutes(router, userController)
+	routes.RegisterAdminRoutes(router, adminController)
+	routes.RegisterModerationRoutes(router, moderationController)
 }
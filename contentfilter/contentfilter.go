@@ -0,0 +1,101 @@
+package contentfilter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Flags explain why a review was held for moderation.
+const (
+	FlagWordlist     = "wordlist"
+	FlagTooManyLinks = "too_many_links"
+	FlagDuplicate    = "duplicate_text"
+	FlagPostingRate  = "posting_rate"
+)
+
+// Submission is a review being created or edited.
+type Submission struct {
+	UserID   primitive.ObjectID
+	ReviewID primitive.ObjectID // zero for a new review
+	Text     string
+}
+
+func (s Submission) IsNew() bool {
+	return s.ReviewID.IsZero()
+}
+
+// Decision is the outcome of running a submission through a Chain. Text is
+// what should be stored, which may have been masked, and Flags lists the
+// reasons, if any, to hold the review for moderation.
+type Decision struct {
+	Text  string
+	Flags []string
+}
+
+func (d Decision) Pending() bool {
+	return len(d.Flags) > 0
+}
+
+func (d *Decision) flag(reason string) {
+	for _, flag := range d.Flags {
+		if flag == reason {
+			return
+		}
+	}
+	d.Flags = append(d.Flags, reason)
+}
+
+// RejectedError is returned when a filter refuses a submission outright.
+// Its message is safe to show to the author.
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return e.Reason
+}
+
+// Filter inspects a submission. It may rewrite decision.Text, add flags to
+// the decision or reject the submission by returning a *RejectedError.
+// Other errors mean the filter could not do its job.
+type Filter interface {
+	Check(ctx context.Context, sub Submission, decision *Decision) error
+}
+
+// Chain runs filters in order, each one seeing the text as left by the one
+// before it. It stops at the first error.
+type Chain []Filter
+
+func (c Chain) Run(ctx context.Context, sub Submission) (Decision, error) {
+	decision := Decision{Text: sub.Text}
+	for _, filter := range c {
+		if err := filter.Check(ctx, sub, &decision); err != nil {
+			return decision, err
+		}
+	}
+	return decision, nil
+}
+
+// Fingerprint reduces review text to a hash that ignores case, punctuation
+// and spacing, so that lightly edited copies of the same text still match.
+// It returns "" for text too short to be worth comparing.
+func Fingerprint(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() < minFingerprintLength {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+const minFingerprintLength = 40
@@ -0,0 +1,63 @@
+package contentfilter
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DuplicateText flags reviews whose text matches another live review, by
+// anyone, using the text_hash stored from Fingerprint.
+type DuplicateText struct {
+	Reviews *mongo.Collection
+}
+
+func (d DuplicateText) Check(ctx context.Context, sub Submission, decision *Decision) error {
+	fingerprint := Fingerprint(decision.Text)
+	if fingerprint == "" {
+		return nil
+	}
+
+	count, err := d.Reviews.CountDocuments(ctx, bson.M{
+		"text_hash":  fingerprint,
+		"_id":        bson.M{"$ne": sub.ReviewID},
+		"deleted_at": bson.M{"$exists": false},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		decision.flag(FlagDuplicate)
+	}
+	return nil
+}
+
+// PostingRate flags a new review when its author has already written Max
+// reviews within Window. Deleted reviews count too, so that deleting and
+// reposting does not reset the limit.
+type PostingRate struct {
+	Reviews *mongo.Collection
+	Max     int64
+	Window  time.Duration
+}
+
+func (p PostingRate) Check(ctx context.Context, sub Submission, decision *Decision) error {
+	if !sub.IsNew() {
+		return nil
+	}
+
+	count, err := p.Reviews.CountDocuments(ctx, bson.M{
+		"user_id":    sub.UserID,
+		"created_at": bson.M{"$gte": time.Now().Add(-p.Window)},
+	})
+	if err != nil {
+		return err
+	}
+	if count >= p.Max {
+		decision.flag(FlagPostingRate)
+	}
+	return nil
+}
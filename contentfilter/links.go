@@ -0,0 +1,20 @@
+package contentfilter
+
+import (
+	"context"
+	"regexp"
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// LinkLimit flags reviews containing more than Max links.
+type LinkLimit struct {
+	Max int
+}
+
+func (l LinkLimit) Check(ctx context.Context, sub Submission, decision *Decision) error {
+	if len(linkPattern.FindAllStringIndex(decision.Text, -1)) > l.Max {
+		decision.flag(FlagTooManyLinks)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package contentfilter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Word actions for a Wordlist entry.
+const (
+	ActionMask   = "mask"
+	ActionFlag   = "flag"
+	ActionReject = "reject"
+)
+
+// Wordlist masks, flags or rejects reviews containing listed words. Words
+// match whole words only and ignore case.
+type Wordlist struct {
+	actions map[string]string
+	pattern *regexp.Regexp
+}
+
+// ParseWordlist reads one entry per line in the form "word" or
+// "word=action", where action is mask (the default), flag or reject. Blank
+// lines and lines starting with # are ignored.
+func ParseWordlist(r io.Reader) (*Wordlist, error) {
+	actions := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		word, action, found := strings.Cut(entry, "=")
+		word = strings.ToLower(strings.TrimSpace(word))
+		action = strings.TrimSpace(action)
+		if !found {
+			action = ActionMask
+		}
+		switch action {
+		case ActionMask, ActionFlag, ActionReject:
+		default:
+			return nil, fmt.Errorf("wordlist line %d: unknown action %q", line, action)
+		}
+		if word == "" {
+			return nil, fmt.Errorf("wordlist line %d: missing word", line)
+		}
+		actions[word] = action
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewWordlist(actions), nil
+}
+
+// NewWordlist builds a wordlist from a map of word to action.
+func NewWordlist(actions map[string]string) *Wordlist {
+	w := &Wordlist{actions: map[string]string{}}
+	var quoted []string
+	for word, action := range actions {
+		word = strings.ToLower(word)
+		w.actions[word] = action
+		quoted = append(quoted, regexp.QuoteMeta(word))
+	}
+	if len(quoted) > 0 {
+		w.pattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	}
+	return w
+}
+
+func (w *Wordlist) Check(ctx context.Context, sub Submission, decision *Decision) error {
+	if w.pattern == nil {
+		return nil
+	}
+
+	for _, match := range w.pattern.FindAllString(decision.Text, -1) {
+		switch w.actions[strings.ToLower(match)] {
+		case ActionReject:
+			return &RejectedError{Reason: "Review contains language that is not allowed"}
+		case ActionFlag:
+			decision.flag(FlagWordlist)
+		}
+	}
+
+	decision.Text = w.pattern.ReplaceAllStringFunc(decision.Text, func(match string) string {
+		if w.actions[strings.ToLower(match)] != ActionMask {
+			return match
+		}
+		first, size := utf8.DecodeRuneInString(match)
+		return string(first) + strings.Repeat("*", utf8.RuneCountInString(match[size:]))
+	})
+	return nil
+}
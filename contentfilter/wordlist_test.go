@@ -0,0 +1,103 @@
+package contentfilter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseWordlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "default action",
+			input: "darn\n",
+			want:  map[string]string{"darn": ActionMask},
+		},
+		{
+			name:  "actions, comments and blank lines",
+			input: "# words\n\nDarn = flag\nheck=reject\n  gosh=mask  \n",
+			want:  map[string]string{"darn": ActionFlag, "heck": ActionReject, "gosh": ActionMask},
+		},
+		{
+			name:    "unknown action",
+			input:   "darn=delete\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing word",
+			input:   "=flag\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := ParseWordlist(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(w.actions, tt.want) {
+				t.Errorf("actions = %v, want %v", w.actions, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordlistCheck(t *testing.T) {
+	w := NewWordlist(map[string]string{
+		"darn": ActionMask,
+		"heck": ActionFlag,
+		"gosh": ActionReject,
+	})
+
+	tests := []struct {
+		name         string
+		text         string
+		wantText     string
+		wantFlags    []string
+		wantRejected bool
+	}{
+		{name: "clean", text: "A fine book.", wantText: "A fine book."},
+		{name: "mask keeps first letter", text: "Darn good book.", wantText: "D*** good book."},
+		{name: "whole words only", text: "Darning socks.", wantText: "Darning socks."},
+		{name: "flag", text: "What the heck.", wantText: "What the heck.", wantFlags: []string{FlagWordlist}},
+		{name: "reject", text: "Oh GOSH.", wantRejected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := Decision{Text: tt.text}
+			err := w.Check(context.Background(), Submission{}, &decision)
+
+			var rejected *RejectedError
+			if tt.wantRejected {
+				if !errors.As(err, &rejected) {
+					t.Fatalf("err = %v, want a RejectedError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if decision.Text != tt.wantText {
+				t.Errorf("text = %q, want %q", decision.Text, tt.wantText)
+			}
+			if !reflect.DeepEqual(decision.Flags, tt.wantFlags) {
+				t.Errorf("flags = %v, want %v", decision.Flags, tt.wantFlags)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"spa_media_review/database"
+	"spa_media_review/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AdminController struct {
+	bookCollection    *mongo.Collection
+	reviewCollection  *mongo.Collection
+	userCollection    *mongo.Collection
+	sessionCollection *mongo.Collection
+	trashRetention    time.Duration
+}
+
+func NewAdminController(bookCollection, reviewCollection, userCollection, sessionCollection *mongo.Collection, trashRetention time.Duration) *AdminController {
+	return &AdminController{
+		bookCollection:    bookCollection,
+		reviewCollection:  reviewCollection,
+		userCollection:    userCollection,
+		sessionCollection: sessionCollection,
+		trashRetention:    trashRetention,
+	}
+}
+
+var trashSorts = map[string]string{
+	"deleted_at": "deleted_at",
+}
+
+func (ac *AdminController) GetTrash(ctx *gin.Context) {
+	page, err := parsePageRequest(ctx, trashSorts, "-deleted_at")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"retention_days": int(ac.trashRetention.Hours() / 24)}
+	match := bson.M{"$match": inTrash(bson.M{})}
+
+	switch ctx.DefaultQuery("type", "books") {
+	case "books":
+		books, next, err := fetchPage[models.Book](context.TODO(), ac.bookCollection, page, match)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+			return
+		}
+		response["books"], response["next_cursor"] = books, next
+	case "reviews":
+		reviews, next, err := fetchPage[models.Review](context.TODO(), ac.reviewCollection, page, match)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+			return
+		}
+		response["reviews"], response["next_cursor"] = reviews, next
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trash type"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// BanUser bans a user and signs them out everywhere. Admins cannot be
+// banned.
+func (ac *AdminController) BanUser(ctx *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	set := bson.M{"banned_at": time.Now(), "updated_at": time.Now()}
+	update := bson.M{"$set": set}
+	if reason := strings.TrimSpace(input.Reason); reason != "" {
+		set["ban_reason"] = reason
+	} else {
+		update["$unset"] = bson.M{"ban_reason": ""}
+	}
+
+	var user models.User
+	err = ac.userCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": userID, "is_admin": bson.M{"$ne": true}},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found or is an admin"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to ban user %s: %v", userID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ban user"})
+		return
+	}
+
+	if err := database.RevokeUserTokens(ctx, ac.userCollection, ac.sessionCollection, userID, models.SessionRevokedBan); err != nil {
+		log.Printf("Failed to revoke tokens of banned user %s: %v", userID.Hex(), err)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User banned", "user": gin.H{
+		"_id":        user.ID.Hex(),
+		"username":   user.Username,
+		"banned_at":  user.BannedAt,
+		"ban_reason": user.BanReason,
+	}})
+}
+
+// UnbanUser lifts a ban. The user has to log in again.
+func (ac *AdminController) UnbanUser(ctx *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	result, err := ac.userCollection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$unset": bson.M{"banned_at": "", "ban_reason": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Failed to unban user %s: %v", userID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unban user"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User unbanned"})
+}
+
+// GetUserSessions lists the devices a user is signed in on.
+func (ac *AdminController) GetUserSessions(ctx *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	sessions, err := database.ListSessions(ctx, ac.sessionCollection, userID)
+	if err != nil {
+		log.Printf("Failed to list sessions of user %s: %v", userID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeUserSession signs a user out of one of their sessions.
+func (ac *AdminController) RevokeUserSession(ctx *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	sessionID, err := primitive.ObjectIDFromHex(ctx.Param("sessionId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	revoked, err := database.RevokeSessions(ctx, ac.sessionCollection, bson.M{"_id": sessionID, "user_id": userID}, models.SessionRevokedByAdmin)
+	if err != nil {
+		log.Printf("Failed to revoke session %s: %v", sessionID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	if revoked == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"spa_media_review/middleware"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// currentUserID returns the ID of the user authenticated by AuthMiddleware.
+func currentUserID(ctx *gin.Context) (primitive.ObjectID, bool) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		return primitive.NilObjectID, false
+	}
+	hex, ok := userID.(string)
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	objectID, err := primitive.ObjectIDFromHex(hex)
+	return objectID, err == nil
+}
+
+// actorRole reports whether the current user is acting as the owner of the
+// resource or as a moderator, as decided by RequireOwnerOrAdmin.
+func actorRole(ctx *gin.Context) string {
+	if role := ctx.GetString("actorRole"); role != "" {
+		return role
+	}
+	return middleware.ActorModerator
+}
+
+// revokeCurrentToken stops the access token of the current request from
+// being accepted again.
+func revokeCurrentToken(ctx *gin.Context) error {
+	tokenID := ctx.GetString("tokenID")
+	if tokenID == "" {
+		return nil
+	}
+	expiresAt := ctx.GetTime("tokenExpiresAt")
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(middleware.AccessTokenTTL)
+	}
+	return middleware.RevokeToken(ctx, tokenID, expiresAt)
+}
@@ -2,12 +2,19 @@ package controllers
 
 import (
 	"context"
-	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"regexp"
+	"spa_media_review/database"
+	"spa_media_review/imaging"
 	"spa_media_review/models"
+	"spa_media_review/storage"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -20,38 +27,43 @@ import (
 type BookController struct {
 	bookCollection   *mongo.Collection
 	reviewCollection *mongo.Collection
+	blobStore        storage.BlobStore
 }
 
-func NewBookController(bookCollection, reviewCollection *mongo.Collection) *BookController {
+func NewBookController(bookCollection, reviewCollection *mongo.Collection, blobStore storage.BlobStore) *BookController {
 	return &BookController{
 		bookCollection:   bookCollection,
 		reviewCollection: reviewCollection,
+		blobStore:        blobStore,
 	}
 }
 
 func (bc *BookController) GetBooks(ctx *gin.Context) {
+	page, err := parsePageRequest(ctx, bookSorts, "title")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	query := ctx.Query("q")
-	filter := bson.M{
+	filter := notDeleted(bson.M{
 		"$or": []bson.M{
-			{"title": bson.M{"$regex": query, "$options": "i"}},
+			{"title": literalRegex(query)},
 		},
-	}
-
-	cursor, err := bc.bookCollection.Find(context.TODO(), filter)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	})
+	if !applyRatingFilter(ctx, filter) {
 		return
 	}
-	defer cursor.Close(context.TODO())
 
-	var books []models.Book
-	if err := cursor.All(context.TODO(), &books); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode books"})
+	books, next, err := fetchPage[models.Book](context.TODO(), bc.bookCollection, page, bson.M{"$match": filter})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"books": books,
+		"books":       books,
+		"next_cursor": next,
 	})
 }
 
@@ -69,23 +81,86 @@ func (bc *BookController) GetBookByID(ctx *gin.Context) {
 	}
 
 	var book models.Book
-	if err := bc.bookCollection.FindOne(context.TODO(), bson.M{"_id": objectId}).Decode(&book); err != nil {
+	if err := bc.bookCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objectId})).Decode(&book); err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
+	setETag(ctx, book.Version)
 	ctx.JSON(http.StatusOK, book)
 }
 
-func (bc *BookController) CreateBook(ctx *gin.Context) {
-	maxSize := int64(20 << 20)
-	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize)
+func (bc *BookController) GetBookByISBN(ctx *gin.Context) {
+	isbn, err := models.NormalizeISBN(ctx.Param("isbn"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ISBN"})
+		return
+	}
 
-	if err := ctx.Request.ParseMultipartForm(maxSize); err != nil {
-		if err.Error() == "http: request body too large" {
-			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File size exceeds 10MB limit"})
+	var book models.Book
+	if err := bc.bookCollection.FindOne(context.TODO(), notDeleted(bson.M{"isbn13": isbn})).Decode(&book); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, book)
+}
+
+func (bc *BookController) GetBookCover(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var book models.Book
+	if err := bc.bookCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objectId})).Decode(&book); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	size := ctx.DefaultQuery("size", "full")
+	key := book.ImageKey
+	if len(book.Images) > 0 {
+		variant, ok := book.Images[size]
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cover size"})
 			return
 		}
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form data"})
+		key = variant.Key
+	}
+	if key == "" {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+		return
+	}
+
+	reader, info, err := bc.blobStore.Get(context.TODO(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to read cover for book %s: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read cover"})
+		return
+	}
+	defer reader.Close()
+
+	etag := `"` + info.ETag + `"`
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "public, max-age=300, must-revalidate")
+	ctx.Header("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+
+	if match := ctx.GetHeader("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.DataFromReader(http.StatusOK, info.Size, info.ContentType, reader, nil)
+}
+
+func (bc *BookController) CreateBook(ctx *gin.Context) {
+	image, ok := readCoverUpload(ctx)
+	if !ok {
 		return
 	}
 
@@ -95,28 +170,16 @@ func (bc *BookController) CreateBook(ctx *gin.Context) {
 		Author:      ctx.PostForm("author"),
 		Category:    ctx.PostForm("category"),
 		Description: ctx.PostForm("description"),
+		ISBN10:      ctx.PostForm("isbn10"),
+		ISBN13:      ctx.PostForm("isbn13"),
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+		Version:     1,
 	}
-
-	file, err := ctx.FormFile("image")
-	if err == nil {
-		openFile, err := file.Open()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open image file"})
-			return
-		}
-		defer openFile.Close()
-
-		fileBytes, err := io.ReadAll(openFile)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read image file"})
-			return
-		}
-		book.Image = base64.StdEncoding.EncodeToString(fileBytes)
-	} else if err != http.ErrMissingFile {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get image file"})
-		return
+	if image != nil {
+		// Stands in for the real key, which saveCover sets once the upload
+		// has been processed.
+		book.ImageKey = "pending"
 	}
 
 	if errors := book.Validate(); len(errors) > 0 {
@@ -124,8 +187,19 @@ func (bc *BookController) CreateBook(ctx *gin.Context) {
 		return
 	}
 
-	_, err = bc.bookCollection.InsertOne(context.TODO(), book)
+	if !bc.saveCover(ctx, &book, image) {
+		return
+	}
+
+	_, err := bc.bookCollection.InsertOne(context.TODO(), book)
 	if err != nil {
+		if err := imaging.DeleteCover(context.TODO(), bc.blobStore, book); err != nil {
+			log.Println("Failed to remove orphaned book cover:", err)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			bc.respondISBNConflict(ctx, book.ISBN13)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book"})
 		log.Println("Failed to create book:", err)
 		return
@@ -135,6 +209,73 @@ func (bc *BookController) CreateBook(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, gin.H{"book": book})
 }
 
+// readCoverUpload parses a multipart form and returns the bytes of its
+// optional "image" file. It writes the error response itself and returns
+// false when the request cannot be used.
+func readCoverUpload(ctx *gin.Context) ([]byte, bool) {
+	maxSize := int64(imaging.MaxUploadSize + 1<<20)
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize)
+	tooLarge := fmt.Sprintf("File size exceeds %dMB limit", imaging.MaxUploadSize>>20)
+
+	if err := ctx.Request.ParseMultipartForm(maxSize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+			return nil, false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form data"})
+		return nil, false
+	}
+
+	file, err := ctx.FormFile("image")
+	if err == http.ErrMissingFile {
+		return nil, true
+	} else if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get image file"})
+		return nil, false
+	}
+	if file.Size > imaging.MaxUploadSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return nil, false
+	}
+
+	openFile, err := file.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open image file"})
+		return nil, false
+	}
+	defer openFile.Close()
+
+	image, err := io.ReadAll(openFile)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read image file"})
+		return nil, false
+	}
+	return image, true
+}
+
+// saveCover runs an uploaded image through the processing pipeline and
+// records the stored variants on the book.
+func (bc *BookController) saveCover(ctx *gin.Context, book *models.Book, image []byte) bool {
+	images, err := imaging.SaveCover(context.TODO(), bc.blobStore, book.ID, image)
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return false
+	case errors.Is(err, imaging.ErrTooLarge):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	case err != nil:
+		log.Println("Failed to store book cover:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image file"})
+		return false
+	}
+
+	book.Images = images
+	book.ImageKey = images["full"].Key
+	return true
+}
+
 func (bc *BookController) UpdateBook(ctx *gin.Context) {
 	id := ctx.Param("id")
 	objectId, err := primitive.ObjectIDFromHex(id)
@@ -144,10 +285,11 @@ func (bc *BookController) UpdateBook(ctx *gin.Context) {
 	}
 
 	var book models.Book
-	if err := bc.bookCollection.FindOne(context.TODO(), bson.M{"_id": objectId}).Decode(&book); err != nil {
+	if err := bc.bookCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objectId})).Decode(&book); err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
+	setETag(ctx, book.Version)
 	ctx.JSON(http.StatusOK, book)
 }
 
@@ -173,29 +315,212 @@ func (bc *BookController) EditedBook(ctx *gin.Context) {
 		return
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"title":       updateBook.Title,
-			"author":      updateBook.Author,
-			"category":    updateBook.Category,
-			"description": updateBook.Description,
-			"image":       updateBook.Image,
-			"updated_at":  time.Now(),
-		},
+	var book models.Book
+	if err := bc.bookCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objectId})).Decode(&book); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
 	}
 
-	result := bc.bookCollection.FindOneAndUpdate(
-		context.TODO(),
-		bson.M{"_id": objectId},
-		update,
-		options.FindOneAndUpdate().SetReturnDocument(options.After),
-	)
+	patch := map[string]*string{
+		"title":       &updateBook.Title,
+		"author":      &updateBook.Author,
+		"category":    &updateBook.Category,
+		"description": &updateBook.Description,
+	}
 
-	if result.Err() != nil {
+	updated, ok := bc.applyBookPatch(ctx, book, patch, nil)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Book updated successfully", "book": updated})
+}
+
+var patchableBookFields = map[string]bool{
+	"title":       true,
+	"author":      true,
+	"category":    true,
+	"description": true,
+	"isbn10":      true,
+	"isbn13":      true,
+}
+
+// PatchBook applies a JSON Merge Patch (RFC 7396) to a book. A multipart
+// body is treated as a patch of its form fields and may also replace the
+// cover through the "image" file.
+func (bc *BookController) PatchBook(ctx *gin.Context) {
+	id := ctx.Param("id")
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var book models.Book
+	if err := bc.bookCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objectId})).Decode(&book); err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"message": "Book updated successfully"})
+
+	patch := map[string]*string{}
+	var image []byte
+
+	if ctx.ContentType() == "multipart/form-data" {
+		var ok bool
+		if image, ok = readCoverUpload(ctx); !ok {
+			return
+		}
+		for field, values := range ctx.Request.MultipartForm.Value {
+			if !patchableBookFields[field] {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Field %q cannot be patched", field)})
+				return
+			}
+			value := values[0]
+			patch[field] = &value
+		}
+	} else {
+		var document map[string]json.RawMessage
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&document); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merge patch document"})
+			return
+		}
+		for field, raw := range document {
+			if !patchableBookFields[field] {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Field %q cannot be patched", field)})
+				return
+			}
+			if string(raw) == "null" {
+				patch[field] = nil
+				continue
+			}
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Field %q must be a string", field)})
+				return
+			}
+			patch[field] = &value
+		}
+	}
+
+	updated, ok := bc.applyBookPatch(ctx, book, patch, image)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"book": updated})
+}
+
+// applyBookPatch merges the patched fields into book, validates the fields
+// that changed and writes only those back. A nil value removes the field.
+// The write only succeeds if the book is still at the version it was read at.
+// It writes the error response itself and returns false on failure.
+func (bc *BookController) applyBookPatch(ctx *gin.Context, book models.Book, patch map[string]*string, image []byte) (models.Book, bool) {
+	if !checkIfMatch(ctx, book.Version) {
+		return book, false
+	}
+
+	fields := map[string]*string{
+		"title":       &book.Title,
+		"author":      &book.Author,
+		"category":    &book.Category,
+		"description": &book.Description,
+		"isbn10":      &book.ISBN10,
+		"isbn13":      &book.ISBN13,
+	}
+
+	changed := map[string]bool{}
+	_, patchesISBN10 := patch["isbn10"]
+	_, patchesISBN13 := patch["isbn13"]
+	if patchesISBN10 || patchesISBN13 {
+		// The two ISBN forms describe the same book, so a patch to either
+		// one re-derives the other instead of keeping a stale value.
+		book.ISBN10, book.ISBN13 = "", ""
+		changed["isbn10"], changed["isbn13"] = true, true
+	}
+	for field, value := range patch {
+		*fields[field] = ""
+		if value != nil {
+			*fields[field] = *value
+		}
+		changed[field] = true
+	}
+
+	validationErrors := map[string]string{}
+	for field, message := range book.Validate() {
+		if changed[field] {
+			validationErrors[field] = message
+		}
+	}
+	if len(validationErrors) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"errors": validationErrors})
+		return book, false
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+	for field := range changed {
+		if value := *fields[field]; value != "" {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+
+	previous := book
+	if image != nil {
+		if !bc.saveCover(ctx, &book, image) {
+			return book, false
+		}
+		set["image_key"] = book.ImageKey
+		set["images"] = book.Images
+	}
+
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var updated models.Book
+	err := database.WithTransaction(context.TODO(), bc.bookCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		err := bc.bookCollection.FindOneAndUpdate(
+			sessCtx,
+			versionFilter(book.ID, book.Version),
+			update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+		if err != nil {
+			return err
+		}
+
+		if changed["title"] || changed["author"] || changed["category"] {
+			_, err = database.PropagateBookSummary(sessCtx, bc.reviewCollection, updated.Summary())
+		}
+		return err
+	})
+
+	// Only one of the two covers is referenced by the book now.
+	if image != nil {
+		orphan := book
+		if err == nil {
+			orphan = previous
+		}
+		if err := imaging.DeleteCover(context.TODO(), bc.blobStore, orphan); err != nil {
+			log.Printf("Failed to remove unused cover of book %s: %v", book.ID.Hex(), err)
+		}
+	}
+
+	switch {
+	case err == mongo.ErrNoDocuments:
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": "The resource has been modified since it was fetched"})
+		return book, false
+	case mongo.IsDuplicateKeyError(err):
+		bc.respondISBNConflict(ctx, book.ISBN13)
+		return book, false
+	case err != nil:
+		log.Printf("Failed to update book %s: %v", book.ID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+		return book, false
+	}
+	setETag(ctx, updated.Version)
+	return updated, true
 }
 
 func (bc *BookController) DeleteBookConfirmation(ctx *gin.Context) {
@@ -207,7 +532,7 @@ func (bc *BookController) DeleteBookConfirmation(ctx *gin.Context) {
 		return
 	}
 	var book models.Book
-	if err := bc.bookCollection.FindOne(context.TODO(), bson.M{"_id": objectId}).Decode(&book); err != nil {
+	if err := bc.bookCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objectId})).Decode(&book); err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
@@ -225,55 +550,176 @@ func (bc *BookController) DeleteBook(ctx *gin.Context) {
 
 	log.Printf("Received ID: %s", id)
 
-	_, err = bc.reviewCollection.DeleteMany(context.TODO(), bson.M{"book._id": objectId})
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete associated reviews"})
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized"})
 		return
 	}
+	deletedAt := time.Now().Truncate(time.Millisecond)
 
-	result, err := bc.bookCollection.DeleteOne(context.TODO(), bson.M{"_id": objectId})
+	err = database.WithTransaction(context.TODO(), bc.bookCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		result, err := bc.bookCollection.UpdateOne(sessCtx, notDeleted(bson.M{"_id": objectId}), softDeleteUpdate(deletedAt, userID))
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		_, err = bc.reviewCollection.UpdateMany(sessCtx, notDeleted(bson.M{"book._id": objectId}), softDeleteUpdate(deletedAt, userID))
+		return err
+	})
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to delete book %s: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book and associated reviews"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Book and associated reviews deleted successfully"})
+}
+
+func (bc *BookController) RestoreBook(ctx *gin.Context) {
+	id := ctx.Param("id")
+	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
 		return
 	}
 
-	log.Printf("Delete result: %+v", result)
-	if result.DeletedCount == 0 {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	var book models.Book
+	if err := bc.bookCollection.FindOne(context.TODO(), inTrash(bson.M{"_id": objectId})).Decode(&book); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found in trash"})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"message": "Book and associated reviews deleted successfully"})
+
+	// Only the reviews removed together with the book come back; reviews
+	// that were deleted on their own beforehand stay in the trash.
+	var restored models.Book
+	err = database.WithTransaction(context.TODO(), bc.bookCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		_, err := bc.reviewCollection.UpdateMany(sessCtx, bson.M{"book._id": objectId, "deleted_at": book.DeletedAt}, restoreUpdate())
+		if err != nil {
+			return err
+		}
+
+		return bc.bookCollection.FindOneAndUpdate(
+			sessCtx,
+			inTrash(bson.M{"_id": objectId}),
+			restoreUpdate(),
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&restored)
+	})
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found in trash"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to restore book %s: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore book"})
+		return
+	}
+
+	setETag(ctx, restored.Version)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Book restored successfully", "book": restored})
 }
 
 func (bc *BookController) SearchBooks(ctx *gin.Context) {
+	mode := ctx.DefaultQuery("mode", "regex")
+	if mode != "regex" && mode != "text" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search mode"})
+		return
+	}
+
+	sorts, defaultSort := bookSorts, "title"
+	if mode == "text" {
+		sorts, defaultSort = bookTextSorts, "-relevance"
+	}
+
+	page, err := parsePageRequest(ctx, sorts, defaultSort)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	title := ctx.Query("title")
 	author := ctx.Query("author")
 	category := ctx.Query("category")
 
-	filter := bson.M{}
+	filter := notDeleted(bson.M{})
 
 	if title != "" {
-		filter["title"] = bson.M{"$regex": title, "$options": "i"}
+		filter["title"] = literalRegex(title)
 	}
 	if author != "" {
-		filter["author"] = bson.M{"$regex": author, "$options": "i"}
+		filter["author"] = literalRegex(author)
 	}
 	if category != "" {
-		filter["category"] = bson.M{"$regex": category, "$options": "i"}
+		filter["category"] = literalRegex(category)
+	}
+	if !applyRatingFilter(ctx, filter) {
+		return
 	}
 
-	cursor, err := bc.bookCollection.Find(context.TODO(), filter)
+	stages := []bson.M{{"$match": filter}}
+
+	if mode == "text" {
+		query := strings.TrimSpace(ctx.Query("q"))
+		if query == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required in text mode"})
+			return
+		}
+		// $text handles "quoted phrases" and -negated terms natively.
+		filter["$text"] = bson.M{"$search": query}
+		stages = append(stages, bson.M{"$addFields": bson.M{"score": bson.M{"$meta": "textScore"}}})
+	}
+
+	books, next, err := fetchPage[models.Book](context.TODO(), bc.bookCollection, page, stages...)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search books"})
 		return
 	}
-	defer cursor.Close(context.TODO())
 
-	var books []models.Book
-	if err = cursor.All(context.TODO(), &books); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode books"})
+	ctx.JSON(http.StatusOK, gin.H{"books": books, "next_cursor": next})
+}
+
+// respondISBNConflict answers a write that clashed with the unique ISBN
+// index. Trashed books keep their ISBN until they are purged, so when the
+// other book is in the trash the response says so and links to its restore
+// endpoint.
+func (bc *BookController) respondISBNConflict(ctx *gin.Context, isbn13 string) {
+	var existing models.Book
+	err := bc.bookCollection.FindOne(context.TODO(), bson.M{"isbn13": isbn13}).Decode(&existing)
+	if err == nil && existing.DeletedAt != nil {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":       "A book with this ISBN is in the trash; restore it instead",
+			"book_id":     existing.ID.Hex(),
+			"restore_url": "/api/books/" + existing.ID.Hex() + "/restore",
+		})
 		return
 	}
+	ctx.JSON(http.StatusConflict, gin.H{"error": "A book with this ISBN already exists"})
+}
+
+// applyRatingFilter restricts a book filter to the optional ?min_rating=
+// average. It writes the error response itself and returns false when the
+// parameter is invalid.
+func applyRatingFilter(ctx *gin.Context, filter bson.M) bool {
+	minRating := ctx.Query("min_rating")
+	if minRating == "" {
+		return true
+	}
+	value, err := strconv.ParseFloat(minRating, 64)
+	if err != nil || value < 0 || value > 5 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "min_rating must be a number between 0 and 5"})
+		return false
+	}
+	filter["rating_avg"] = bson.M{"$gte": value}
+	return true
+}
 
-	ctx.JSON(http.StatusOK, gin.H{"books": books})
+// literalRegex builds a case-insensitive substring match that treats any
+// regex metacharacters in the user's input as plain text.
+func literalRegex(value string) bson.M {
+	return bson.M{"$regex": regexp.QuoteMeta(value), "$options": "i"}
 }
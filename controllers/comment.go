@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"spa_media_review/database"
+	"spa_media_review/middleware"
+	"spa_media_review/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CommentController struct {
+	commentCollection *mongo.Collection
+	reviewCollection  *mongo.Collection
+	userCollection    *mongo.Collection
+}
+
+func NewCommentController(commentCollection, reviewCollection, userCollection *mongo.Collection) *CommentController {
+	return &CommentController{
+		commentCollection: commentCollection,
+		reviewCollection:  reviewCollection,
+		userCollection:    userCollection,
+	}
+}
+
+var commentSorts = map[string]string{
+	"created_at": "created_at",
+}
+
+// GetComments lists the top-level comments on a review a page at a time,
+// each with all of its replies. Replies carry parent_id so that clients can
+// nest them and are ordered oldest first.
+func (cc *CommentController) GetComments(ctx *gin.Context) {
+	reviewID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	page, err := parsePageRequest(ctx, commentSorts, "created_at")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var review models.Review
+	if err := cc.reviewCollection.FindOne(context.TODO(), published(bson.M{"_id": reviewID})).Decode(&review); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+
+	match := bson.M{"$match": notDeleted(bson.M{"review_id": reviewID, "parent_id": nil})}
+	roots, next, err := fetchPage[models.Comment](context.TODO(), cc.commentCollection, page, match)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+
+	if len(roots) > 0 {
+		rootIDs := make([]primitive.ObjectID, len(roots))
+		for i, root := range roots {
+			rootIDs[i] = root.ID
+		}
+
+		cursor, err := cc.commentCollection.Find(
+			context.TODO(),
+			notDeleted(bson.M{"ancestors.0": bson.M{"$in": rootIDs}}),
+			options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}),
+		)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch replies"})
+			return
+		}
+
+		var replies []models.Comment
+		if err := cursor.All(context.TODO(), &replies); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse replies"})
+			return
+		}
+
+		byRoot := make(map[primitive.ObjectID][]models.Comment, len(roots))
+		for _, reply := range replies {
+			byRoot[reply.RootID()] = append(byRoot[reply.RootID()], reply)
+		}
+		for i := range roots {
+			roots[i].Replies = byRoot[roots[i].ID]
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"comments":      roots,
+		"comment_count": review.CommentCount,
+		"next_cursor":   next,
+	})
+}
+
+// CreateComment adds a comment to a review, or a reply to another comment
+// on it when parent_id is given.
+func (cc *CommentController) CreateComment(ctx *gin.Context) {
+	reviewID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var input struct {
+		Body     string `json:"body"`
+		ParentID string `json:"parent_id"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized"})
+		return
+	}
+
+	var user models.User
+	if err := cc.userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	comment := models.Comment{
+		ID:        primitive.NewObjectID(),
+		ReviewID:  reviewID,
+		Ancestors: []primitive.ObjectID{},
+		UserID:    userID,
+		Username:  user.Username,
+		Body:      input.Body,
+		CreatedAt: time.Now(),
+		Version:   1,
+	}
+	if errors := comment.Validate(); len(errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"errors": errors})
+		return
+	}
+
+	if input.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(input.ParentID)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent comment ID"})
+			return
+		}
+
+		var parent models.Comment
+		err = cc.commentCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": parentID, "review_id": reviewID})).Decode(&parent)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+			return
+		}
+		comment.ParentID = &parent.ID
+		comment.Ancestors = append(append(comment.Ancestors, parent.Ancestors...), parent.ID)
+	}
+
+	err = database.WithTransaction(context.TODO(), cc.commentCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		result, err := cc.reviewCollection.UpdateOne(sessCtx, published(bson.M{"_id": reviewID}), bson.M{"$inc": bson.M{"comment_count": 1}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		_, err = cc.commentCollection.InsertOne(sessCtx, comment)
+		return err
+	})
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to create comment on review %s: %v", reviewID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		return
+	}
+
+	setETag(ctx, comment.Version)
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Comment created", "comment": comment})
+}
+
+func (cc *CommentController) EditComment(ctx *gin.Context) {
+	commentID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	var comment models.Comment
+	if err := cc.commentCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": commentID})).Decode(&comment); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	if !checkIfMatch(ctx, comment.Version) {
+		return
+	}
+
+	comment.Body = input.Body
+	if errors := comment.Validate(); len(errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"errors": errors})
+		return
+	}
+
+	var updated models.Comment
+	err = cc.commentCollection.FindOneAndUpdate(
+		context.TODO(),
+		versionFilter(commentID, comment.Version),
+		bson.M{
+			"$set": bson.M{"body": comment.Body, "edited_by": actorRole(ctx), "updated_at": time.Now()},
+			"$inc": bson.M{"version": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": "The resource has been modified since it was fetched"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to update comment %s: %v", commentID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	setETag(ctx, updated.Version)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Comment updated successfully", "comment": updated, "actor": actorRole(ctx)})
+}
+
+// DeleteComment moves a comment and all replies beneath it to the trash.
+func (cc *CommentController) DeleteComment(ctx *gin.Context) {
+	commentID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized"})
+		return
+	}
+
+	var comment models.Comment
+	if err := cc.commentCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": commentID})).Decode(&comment); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	deletedAt := time.Now().Truncate(time.Millisecond)
+	err = database.WithTransaction(context.TODO(), cc.commentCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		subtree := notDeleted(bson.M{"$or": []bson.M{{"_id": commentID}, {"ancestors": commentID}}})
+		result, err := cc.commentCollection.UpdateMany(sessCtx, subtree, softDeleteUpdate(deletedAt, userID))
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		_, err = cc.reviewCollection.UpdateOne(sessCtx, bson.M{"_id": comment.ReviewID}, bson.M{"$inc": bson.M{"comment_count": -result.ModifiedCount}})
+		return err
+	})
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to delete comment %s: %v", commentID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully", "actor": actorRole(ctx)})
+}
+
+// CommentOwner returns the ID of the user who wrote the comment named by the
+// :id parameter, for use with middleware.RequireOwnerOrAdmin.
+func (cc *CommentController) CommentOwner(ctx *gin.Context) (string, error) {
+	commentID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		return "", middleware.ErrResourceNotFound
+	}
+
+	var comment struct {
+		UserID primitive.ObjectID `bson:"user_id"`
+	}
+	err = cc.commentCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": commentID})).Decode(&comment)
+	if err == mongo.ErrNoDocuments {
+		return "", middleware.ErrResourceNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return comment.UserID.Hex(), nil
+}
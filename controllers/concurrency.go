@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func setETag(ctx *gin.Context, version int64) {
+	ctx.Header("ETag", versionETag(version))
+}
+
+// checkIfMatch compares the request's If-Match header with the current
+// version of a document. A missing header is accepted unless
+// REQUIRE_IF_MATCH is enabled, in which case the request fails with 428.
+// It writes the error response itself and returns false on failure.
+func checkIfMatch(ctx *gin.Context, current int64) bool {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		if required, _ := strconv.ParseBool(os.Getenv("REQUIRE_IF_MATCH")); required {
+			ctx.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+			return false
+		}
+		return true
+	}
+
+	// If-Match uses the strong comparison, so weak tags never match.
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == versionETag(current) {
+			return true
+		}
+	}
+
+	setETag(ctx, current)
+	ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": "The resource has been modified since it was fetched"})
+	return false
+}
+
+// versionFilter matches a document only while it is still at the given
+// version. Documents written before versioning have no field and count as 0.
+func versionFilter(id primitive.ObjectID, version int64) bson.M {
+	if version == 0 {
+		return bson.M{"_id": id, "version": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"_id": id, "version": version}
+}
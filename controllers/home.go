@@ -24,6 +24,12 @@ func NewHomeController(bookCollection, userCollection *mongo.Collection) *HomeCo
 }
 
 func (hc *HomeController) GetHome(ctx *gin.Context) {
+	page, err := parsePageRequest(ctx, bookSorts, "title")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	userID, exists := ctx.Get("userID")
 	var username string
 	if exists {
@@ -35,27 +41,21 @@ func (hc *HomeController) GetHome(ctx *gin.Context) {
 	}
 
 	query := ctx.Query("q")
-	filter := bson.M{
+	filter := notDeleted(bson.M{
 		"$or": []bson.M{
-			{"title": bson.M{"$regex": query, "$options": "i"}},
+			{"title": literalRegex(query)},
 		},
-	}
+	})
 
-	cursor, err := hc.bookCollection.Find(context.TODO(), filter)
+	books, next, err := fetchPage[models.Book](context.TODO(), hc.bookCollection, page, bson.M{"$match": filter})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
 		return
 	}
-	defer cursor.Close(context.TODO())
-
-	var books []models.Book
-	if err := cursor.All(context.TODO(), &books); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode books"})
-		return
-	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"books":    books,
-		"username": username,
+		"books":       books,
+		"next_cursor": next,
+		"username":    username,
 	})
 }
 
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"spa_media_review/database"
+	"spa_media_review/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const defaultReportHideThreshold = 3
+
+var errNothingToModerate = errors.New("no open reports and not pending")
+
+type ModerationController struct {
+	reportCollection *mongo.Collection
+	reviews          *ReviewController
+}
+
+func NewModerationController(reportCollection *mongo.Collection, reviews *ReviewController) *ModerationController {
+	return &ModerationController{reportCollection: reportCollection, reviews: reviews}
+}
+
+// published restricts a filter to reviews that anyone may see.
+func published(filter bson.M) bson.M {
+	filter["status"] = bson.M{"$nin": models.UnpublishedReviewStatuses}
+	return notDeleted(filter)
+}
+
+// reportHideThreshold is the number of open reports that hides a review
+// until an admin looks at it, set with REPORT_HIDE_THRESHOLD.
+func reportHideThreshold() int64 {
+	threshold, err := strconv.ParseInt(os.Getenv("REPORT_HIDE_THRESHOLD"), 10, 64)
+	if err != nil || threshold < 1 {
+		return defaultReportHideThreshold
+	}
+	return threshold
+}
+
+// ReportReview files the current user's report against a review and hides
+// the review once it has collected enough open reports.
+func (mc *ModerationController) ReportReview(ctx *gin.Context) {
+	reviewID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var input struct {
+		Reason  string `json:"reason" binding:"required"`
+		Details string `json:"details"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized"})
+		return
+	}
+
+	report := models.Report{
+		ID:         primitive.NewObjectID(),
+		ReviewID:   reviewID,
+		ReporterID: userID,
+		Reason:     input.Reason,
+		Details:    input.Details,
+		Status:     models.ReportOpen,
+		CreatedAt:  time.Now(),
+	}
+	if errors := report.Validate(); len(errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"errors": errors})
+		return
+	}
+
+	var review models.Review
+	if err := mc.reviews.reviewCollection.FindOne(context.TODO(), published(bson.M{"_id": reviewID})).Decode(&review); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if review.UserID == userID {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You cannot report your own review"})
+		return
+	}
+
+	err = database.WithTransaction(context.TODO(), mc.reportCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		if _, err := mc.reportCollection.InsertOne(sessCtx, report); err != nil {
+			return err
+		}
+
+		open, err := mc.reportCollection.CountDocuments(sessCtx, bson.M{"review_id": reviewID, "status": models.ReportOpen})
+		if err != nil || open < reportHideThreshold() {
+			return err
+		}
+
+		_, err = mc.reviews.setReviewStatus(sessCtx, published(bson.M{"_id": reviewID}), models.ReviewHidden)
+		if err == mongo.ErrNoDocuments {
+			// Already hidden or deleted.
+			return nil
+		}
+		return err
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "You have already reported this review"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to report review %s: %v", reviewID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report review"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Thank you, the review has been reported", "report": report})
+}
+
+var pendingSorts = map[string]string{
+	"created_at": "created_at",
+}
+
+// GetPendingReviews lists reviews that the content filter held back, oldest
+// first, with the flags that explain why.
+func (mc *ModerationController) GetPendingReviews(ctx *gin.Context) {
+	page, err := parsePageRequest(ctx, pendingSorts, "created_at")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	match := bson.M{"$match": notDeleted(bson.M{"status": models.ReviewPending})}
+	reviews, next, err := fetchPage[models.Review](context.TODO(), mc.reviews.reviewCollection, page, match)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending reviews"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"reviews": reviews, "next_cursor": next})
+}
+
+// moderationItem is one entry in the moderation queue: a review together
+// with its open reports.
+type moderationItem struct {
+	ReviewID        primitive.ObjectID `json:"review_id" bson:"_id"`
+	ReportCount     int64              `json:"report_count" bson:"report_count"`
+	Reasons         []string           `json:"reasons" bson:"reasons"`
+	FirstReportedAt time.Time          `json:"first_reported_at" bson:"first_reported_at"`
+	LastReportedAt  time.Time          `json:"last_reported_at" bson:"last_reported_at"`
+	Review          models.Review      `json:"review" bson:"review"`
+	Reports         []models.Report    `json:"reports" bson:"reports"`
+}
+
+var moderationSorts = map[string]string{
+	"reports":     "report_count",
+	"reported_at": "last_reported_at",
+}
+
+// GetModerationQueue lists reviews with open reports, most reported first.
+// Reports on reviews that have since been deleted are left out.
+func (mc *ModerationController) GetModerationQueue(ctx *gin.Context) {
+	page, err := parsePageRequest(ctx, moderationSorts, "-reports")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, next, err := fetchPage[moderationItem](context.TODO(), mc.reportCollection, page,
+		bson.M{"$match": bson.M{"status": models.ReportOpen}},
+		bson.M{"$sort": bson.M{"created_at": 1}},
+		bson.M{"$group": bson.M{
+			"_id":               "$review_id",
+			"report_count":      bson.M{"$sum": 1},
+			"reasons":           bson.M{"$addToSet": "$reason"},
+			"first_reported_at": bson.M{"$min": "$created_at"},
+			"last_reported_at":  bson.M{"$max": "$created_at"},
+			"reports":           bson.M{"$push": "$$ROOT"},
+		}},
+		bson.M{"$lookup": bson.M{
+			"from":         mc.reviews.reviewCollection.Name(),
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "review",
+		}},
+		bson.M{"$unwind": "$review"},
+		bson.M{"$match": bson.M{"review.deleted_at": bson.M{"$exists": false}}},
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation queue"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"items":          items,
+		"next_cursor":    next,
+		"hide_threshold": reportHideThreshold(),
+	})
+}
+
+// ResolveReports settles a reported or pending review. It closes every open
+// report on it, recording the admin's decision, and applies the decision:
+// approve publishes the review, hide hides it and delete moves it to the
+// trash.
+func (mc *ModerationController) ResolveReports(ctx *gin.Context) {
+	reviewID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var input struct {
+		Action string `json:"action" binding:"required"`
+		Note   string `json:"note"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	switch input.Action {
+	case models.ModerationApprove, models.ModerationHide, models.ModerationDelete:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Action must be approve, hide or delete"})
+		return
+	}
+
+	adminID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized"})
+		return
+	}
+
+	now := time.Now().Truncate(time.Millisecond)
+	resolution := models.Resolution{Action: input.Action, Note: input.Note, ResolvedBy: adminID, ResolvedAt: now}
+
+	var review models.Review
+	var resolved int64
+	err = database.WithTransaction(context.TODO(), mc.reportCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		result, err := mc.reportCollection.UpdateMany(
+			sessCtx,
+			bson.M{"review_id": reviewID, "status": models.ReportOpen},
+			bson.M{"$set": bson.M{"status": models.ReportResolved, "resolution": resolution}},
+		)
+		if err != nil {
+			return err
+		}
+		resolved = result.ModifiedCount
+		if resolved == 0 {
+			pending, err := mc.reviews.reviewCollection.CountDocuments(sessCtx, notDeleted(bson.M{"_id": reviewID, "status": models.ReviewPending}))
+			if err != nil {
+				return err
+			}
+			if pending == 0 {
+				return errNothingToModerate
+			}
+		}
+
+		switch input.Action {
+		case models.ModerationApprove:
+			review, err = mc.reviews.setReviewStatus(sessCtx, notDeleted(bson.M{"_id": reviewID}), models.ReviewPublished)
+		case models.ModerationHide:
+			review, err = mc.reviews.setReviewStatus(sessCtx, notDeleted(bson.M{"_id": reviewID}), models.ReviewHidden)
+		case models.ModerationDelete:
+			review, err = mc.reviews.trashReview(sessCtx, reviewID, now, adminID)
+		}
+		return err
+	})
+	switch {
+	case err == errNothingToModerate:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "This review has no open reports and is not awaiting moderation"})
+		return
+	case err == mongo.ErrNoDocuments:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	case err != nil:
+		log.Printf("Failed to resolve reports on review %s: %v", reviewID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve reports"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":          "Reports resolved",
+		"resolution":       resolution,
+		"resolved_reports": resolved,
+		"review":           review,
+	})
+}
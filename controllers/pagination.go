@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var bookSorts = map[string]string{
+	"title":      "title",
+	"author":     "author",
+	"created_at": "created_at",
+	"rating":     "rating_avg",
+}
+
+var bookTextSorts = map[string]string{
+	"relevance":  "score",
+	"title":      "title",
+	"author":     "author",
+	"created_at": "created_at",
+	"rating":     "rating_avg",
+}
+
+// sortDefaults are the values sorted on for documents that lack an optional
+// sort field, such as books whose ratings were never computed. Without them
+// the cursor would hold null, which never compares below or above a number,
+// and paging would stop early.
+var sortDefaults = map[string]interface{}{
+	"rating_avg": 0.0,
+}
+
+// pageRequest describes one page of a keyset-paginated listing. The sort
+// field is always paired with _id so that the ordering is stable.
+type pageRequest struct {
+	limit int64
+	sort  string
+	field string
+	desc  bool
+	after *pageCursor
+}
+
+// pageCursor is the decoded form of the opaque next_cursor token.
+type pageCursor struct {
+	Sort  string        `bson:"s"`
+	Value bson.RawValue `bson:"v"`
+	ID    bson.RawValue `bson:"i"`
+}
+
+func parsePageRequest(ctx *gin.Context, sorts map[string]string, defaultSort string) (pageRequest, error) {
+	page := pageRequest{limit: defaultPageSize}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n < 1 {
+			return page, errors.New("Invalid limit parameter")
+		}
+		page.limit = min(n, maxPageSize)
+	}
+
+	page.sort = ctx.DefaultQuery("sort", defaultSort)
+	field, ok := sorts[strings.TrimPrefix(page.sort, "-")]
+	if !ok {
+		return page, errors.New("Invalid sort parameter")
+	}
+	page.field = field
+	page.desc = strings.HasPrefix(page.sort, "-")
+
+	if after := ctx.Query("after"); after != "" {
+		data, err := base64.RawURLEncoding.DecodeString(after)
+		if err != nil {
+			return page, errors.New("Invalid cursor")
+		}
+		var cursor pageCursor
+		if err := bson.Unmarshal(data, &cursor); err != nil || cursor.Sort != page.sort {
+			return page, errors.New("Invalid cursor")
+		}
+		page.after = &cursor
+	}
+
+	return page, nil
+}
+
+// pipeline appends the keyset match, sort and limit stages to the caller's
+// stages. One extra document is requested to detect whether a next page exists.
+func (p pageRequest) pipeline(stages []bson.M) []bson.M {
+	direction, op := 1, "$gt"
+	if p.desc {
+		direction, op = -1, "$lt"
+	}
+
+	pipeline := append([]bson.M{}, stages...)
+	if def, ok := sortDefaults[p.field]; ok {
+		pipeline = append(pipeline, bson.M{"$addFields": bson.M{p.field: bson.M{"$ifNull": bson.A{"$" + p.field, def}}}})
+	}
+	if p.after != nil {
+		pipeline = append(pipeline, bson.M{"$match": bson.M{
+			"$or": []bson.M{
+				{p.field: bson.M{op: p.after.Value}},
+				{p.field: p.after.Value, "_id": bson.M{op: p.after.ID}},
+			},
+		}})
+	}
+
+	return append(pipeline,
+		bson.M{"$sort": bson.D{{Key: p.field, Value: direction}, {Key: "_id", Value: direction}}},
+		bson.M{"$limit": p.limit + 1},
+	)
+}
+
+func (p pageRequest) cursorFor(doc bson.Raw) (string, error) {
+	value, err := doc.LookupErr(strings.Split(p.field, ".")...)
+	if err != nil {
+		value = bson.RawValue{Type: bsontype.Null}
+	}
+
+	data, err := bson.Marshal(pageCursor{Sort: p.sort, Value: value, ID: doc.Lookup("_id")})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// fetchPage runs the paginated aggregation and returns the decoded page along
+// with the cursor for the next one, which is empty on the last page.
+func fetchPage[T any](ctx context.Context, collection *mongo.Collection, page pageRequest, stages ...bson.M) ([]T, string, error) {
+	cursor, err := collection.Aggregate(ctx, page.pipeline(stages))
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.Raw
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if int64(len(docs)) > page.limit {
+		docs = docs[:page.limit]
+		if next, err = page.cursorFor(docs[len(docs)-1]); err != nil {
+			return nil, "", err
+		}
+	}
+
+	items := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		var item T
+		if err := bson.Unmarshal(doc, &item); err != nil {
+			return nil, "", err
+		}
+		items = append(items, item)
+	}
+	return items, next, nil
+}
@@ -2,9 +2,15 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"spa_media_review/contentfilter"
+	"spa_media_review/database"
+	"spa_media_review/middleware"
 	"spa_media_review/models"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,36 +21,33 @@ import (
 )
 
 type ReviewController struct {
-	reviewCollection *mongo.Collection
-	bookCollection   *mongo.Collection
-	userCollection   *mongo.Collection
+	reviewCollection  *mongo.Collection
+	bookCollection    *mongo.Collection
+	userCollection    *mongo.Collection
+	voteCollection    *mongo.Collection
+	commentCollection *mongo.Collection
+	filter            contentfilter.Chain
 }
 
-func NewReviewController(reviewCollection, bookCollection, userCollection *mongo.Collection) *ReviewController {
+func NewReviewController(reviewCollection, bookCollection, userCollection, voteCollection, commentCollection *mongo.Collection, filter contentfilter.Chain) *ReviewController {
 	return &ReviewController{
-		reviewCollection: reviewCollection,
-		bookCollection:   bookCollection,
-		userCollection:   userCollection,
+		reviewCollection:  reviewCollection,
+		bookCollection:    bookCollection,
+		userCollection:    userCollection,
+		voteCollection:    voteCollection,
+		commentCollection: commentCollection,
+		filter:            filter,
 	}
 }
 
 func (rc *ReviewController) GetReviews(ctx *gin.Context) {
+	hideSpoilers, ok := spoilerMode(ctx)
+	if !ok {
+		return
+	}
+
 	var reviews []models.Review
-	cursor, err := rc.reviewCollection.Aggregate(context.TODO(), []bson.M{
-		{
-			"$lookup": bson.M{
-				"from":         "users",
-				"localField":   "user_id",
-				"foreignField": "_id",
-				"as":           "user_info",
-			},
-		},
-		{
-			"$addFields": bson.M{
-				"username": bson.M{"$arrayElemAt": []interface{}{"$user_info.username", 0}},
-			},
-		},
-	})
+	cursor, err := rc.reviewCollection.Find(context.TODO(), published(bson.M{}))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
 		return
@@ -55,14 +58,11 @@ func (rc *ReviewController) GetReviews(ctx *gin.Context) {
 		return
 	}
 
-	for i, review := range reviews {
-		var user models.User
-		err := rc.userCollection.FindOne(context.TODO(), bson.M{"_id": review.UserID}).Decode(&user)
-		if err == nil {
-			reviews[i].Username = user.Username
-		} else {
+	for i := range reviews {
+		if reviews[i].Username == "" {
 			reviews[i].Username = "Unknown User"
 		}
+		reviews[i].Render(hideSpoilers)
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"reviews": reviews})
@@ -78,7 +78,7 @@ func (rc *ReviewController) NewReview(ctx *gin.Context) {
 	}
 
 	var book models.Book
-	if err := rc.bookCollection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&book); err != nil {
+	if err := rc.bookCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objID})).Decode(&book); err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
@@ -97,21 +97,32 @@ func (rc *ReviewController) NewReview(ctx *gin.Context) {
 		return
 	}
 
+	// The form is pre-filled with the user's existing review, if any.
+	var existing *models.Review
+	var review models.Review
+	if err := rc.reviewCollection.FindOne(context.TODO(), notDeleted(bson.M{"user_id": userObjID, "book._id": objID})).Decode(&review); err == nil {
+		existing = &review
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
-		"book": book,
-		"user": gin.H{
-			"id":       user.ID.Hex(),
-			"username": user.Username,
-		},
+		"book":              book,
+		"user":              user.Public(),
+		"review":            existing,
+		"rating_dimensions": models.RatingDimensionsFor(book.Category),
 	})
 }
 
+// reviewInput is the body of a request to create a review.
+type reviewInput struct {
+	BookID           string             `json:"book_id" binding:"required"`
+	Review           string             `json:"review"`
+	Rating           float64            `json:"rating" binding:"required,min=1,max=5"`
+	Ratings          map[string]float64 `json:"ratings"`
+	ContainsSpoilers bool               `json:"contains_spoilers"`
+}
+
 func (rc *ReviewController) CreateReview(ctx *gin.Context) {
-	var input struct {
-		BookID string `json:"book_id" binding:"required"`
-		Review string `json:"review"`
-		Rating int    `json:"rating" binding:"required,min=1,max=5"`
-	}
+	var input reviewInput
 
 	if err := ctx.BindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
@@ -125,11 +136,16 @@ func (rc *ReviewController) CreateReview(ctx *gin.Context) {
 	}
 
 	var book models.Book
-	if err := rc.bookCollection.FindOne(context.TODO(), bson.M{"_id": bookID}).Decode(&book); err != nil {
+	if err := rc.bookCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": bookID})).Decode(&book); err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
 
+	if errors := models.ValidateRatings(input.Rating, input.Ratings, book.Category); len(errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"errors": errors})
+		return
+	}
+
 	userID, exists := ctx.Get("userID")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized"})
@@ -148,32 +164,292 @@ func (rc *ReviewController) CreateReview(ctx *gin.Context) {
 		return
 	}
 
-	newReview := models.Review{
-		ID:        primitive.NewObjectID(),
-		UserID:    objectID,
-		Username:  user.Username,
-		Review:    input.Review,
-		Rating:    input.Rating,
-		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
-		Book:      book,
-		User:      user,
+	if required, _ := strconv.ParseBool(os.Getenv("REQUIRE_EMAIL_VERIFICATION")); required && !user.EmailVerified {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address before posting reviews"})
+		return
 	}
 
-	_, err = rc.reviewCollection.InsertOne(context.TODO(), newReview)
+	upsert, _ := strconv.ParseBool(ctx.Query("upsert"))
+
+	var existing models.Review
+	err = rc.reviewCollection.FindOne(context.TODO(), bson.M{"user_id": objectID, "book._id": bookID}).Decode(&existing)
+	switch {
+	case err == mongo.ErrNoDocuments:
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		log.Println("Failed to look up existing review:", err)
+		return
+	case existing.DeletedAt != nil && (existing.DeletedBy == nil || *existing.DeletedBy != objectID):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Your review of this book was removed by a moderator"})
+		return
+	case existing.DeletedAt == nil && !upsert:
+		respondReviewExists(ctx, existing.ID)
+		return
+	default:
+		rc.overwriteReview(ctx, existing, book, user, input)
+		return
+	}
+
+	decision, ok := rc.screenReview(ctx, contentfilter.Submission{UserID: objectID, Text: input.Review})
+	if !ok {
+		return
+	}
+
+	newReview := models.Review{
+		ID:               primitive.NewObjectID(),
+		UserID:           objectID,
+		Username:         user.Username,
+		Review:           decision.Text,
+		TextHash:         contentfilter.Fingerprint(decision.Text),
+		Rating:           input.Rating,
+		Ratings:          input.Ratings,
+		ContainsSpoilers: input.ContainsSpoilers,
+		CreatedAt:        primitive.NewDateTimeFromTime(time.Now()),
+		Version:          1,
+		Book:             book.Summary(),
+	}
+	if decision.Pending() {
+		newReview.Status = models.ReviewPending
+		newReview.Flags = decision.Flags
+	}
+
+	err = database.WithTransaction(context.TODO(), rc.reviewCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		if _, err := rc.reviewCollection.InsertOne(sessCtx, newReview); err != nil {
+			return err
+		}
+		return database.ApplyRatingChange(sessCtx, rc.bookCollection, book.ID, database.RatingContribution{}, ratingContribution(newReview))
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		// Another request created the review between the lookup and the insert.
+		if err := rc.reviewCollection.FindOne(context.TODO(), bson.M{"user_id": objectID, "book._id": bookID}).Decode(&existing); err == nil {
+			respondReviewExists(ctx, existing.ID)
+			return
+		}
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
 		log.Println("Failed to create review:", err)
 		return
 	}
 
+	newReview.Render(false)
 	ctx.JSON(http.StatusCreated, gin.H{
-		"message": "Review created",
+		"message": reviewSavedMessage("Review created", newReview),
 		"review":  newReview,
-		"user": gin.H{
-			"id":       user.ID.Hex(),
-			"username": user.Username,
-		},
+		"user":    user.Public(),
+	})
+}
+
+// overwriteReview replaces the content of the user's existing review of a
+// book. A review the user deleted themselves is brought back as a new one,
+// without the votes and comments it had collected.
+func (rc *ReviewController) overwriteReview(ctx *gin.Context, existing models.Review, book models.Book, user models.User, input reviewInput) {
+	// A revived review is screened as a new one, so that it counts against
+	// the posting rate like any other new review.
+	sub := contentfilter.Submission{UserID: user.ID, ReviewID: existing.ID, Text: input.Review}
+	if existing.DeletedAt != nil {
+		sub.ReviewID = primitive.NilObjectID
+	}
+	decision, ok := rc.screenReview(ctx, sub)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"rating":            input.Rating,
+		"contains_spoilers": input.ContainsSpoilers,
+		"username":          user.Username,
+		"book":              book.Summary(),
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}, "$unset": bson.M{}}
+	setSubRatings(update, input.Ratings)
+
+	status, message := http.StatusOK, "Review updated"
+	if existing.DeletedAt != nil {
+		set["created_at"] = now
+		set["helpful_count"] = 0
+		set["unhelpful_count"] = 0
+		set["comment_count"] = 0
+		unset := update["$unset"].(bson.M)
+		for _, field := range []string{"deleted_at", "deleted_by", "updated_at", "edited_by"} {
+			unset[field] = ""
+		}
+		status, message = http.StatusCreated, "Review created"
+	} else {
+		set["updated_at"] = now
+		set["edited_by"] = middleware.ActorOwner
+	}
+	applyDecision(update, existing, decision)
+
+	var review models.Review
+	err := database.WithTransaction(context.TODO(), rc.reviewCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		err := rc.reviewCollection.FindOneAndUpdate(
+			sessCtx,
+			versionFilter(existing.ID, existing.Version),
+			update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&review)
+		if err != nil {
+			return err
+		}
+		if existing.DeletedAt != nil {
+			if _, err := rc.voteCollection.DeleteMany(sessCtx, bson.M{"review_id": existing.ID}); err != nil {
+				return err
+			}
+		}
+		return database.ApplyRatingChange(sessCtx, rc.bookCollection, book.ID, ratingContribution(existing), ratingContribution(review))
 	})
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "The review was changed by another request, please try again"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save review"})
+		log.Printf("Failed to overwrite review %s: %v", existing.ID.Hex(), err)
+		return
+	}
+
+	review.Render(false)
+	setETag(ctx, review.Version)
+	ctx.JSON(status, gin.H{
+		"message": reviewSavedMessage(message, review),
+		"review":  review,
+		"user":    user.Public(),
+	})
+}
+
+// screenReview runs review text through the content filter. It writes the
+// error response itself and returns false if the review is rejected or
+// cannot be checked.
+func (rc *ReviewController) screenReview(ctx *gin.Context, sub contentfilter.Submission) (contentfilter.Decision, bool) {
+	decision, err := rc.filter.Run(context.TODO(), sub)
+	var rejected *contentfilter.RejectedError
+	if errors.As(err, &rejected) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": rejected.Reason})
+		return decision, false
+	} else if err != nil {
+		log.Printf("Content filter failed: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check review"})
+		return decision, false
+	}
+	return decision, true
+}
+
+// applyDecision adds screened text to a review update. A flagged review
+// that is currently published is held for moderation; hidden and pending
+// reviews keep their status. Flags left over from an earlier version are
+// cleared when the text passes. The update must have a $set and an $unset,
+// which is dropped again if it ends up empty.
+func applyDecision(update bson.M, current models.Review, decision contentfilter.Decision) {
+	set, unset := update["$set"].(bson.M), update["$unset"].(bson.M)
+
+	set["review"] = decision.Text
+	if hash := contentfilter.Fingerprint(decision.Text); hash != "" {
+		set["text_hash"] = hash
+	} else {
+		unset["text_hash"] = ""
+	}
+
+	if decision.Pending() {
+		set["flags"] = decision.Flags
+		if current.Published() {
+			set["status"] = models.ReviewPending
+		}
+	} else {
+		unset["flags"] = ""
+	}
+
+	if len(unset) == 0 {
+		delete(update, "$unset")
+	}
+}
+
+// setSubRatings stores the sub-ratings in a review update, or removes them
+// when there are none. The update must have a $set and an $unset.
+func setSubRatings(update bson.M, ratings map[string]float64) {
+	if len(ratings) > 0 {
+		update["$set"].(bson.M)["ratings"] = ratings
+	} else {
+		update["$unset"].(bson.M)["ratings"] = ""
+	}
+}
+
+func reviewSavedMessage(message string, review models.Review) string {
+	if review.Status == models.ReviewPending {
+		return message + " and is awaiting moderation"
+	}
+	return message
+}
+
+func reviewURL(id primitive.ObjectID) string {
+	return "/api/reviews/" + id.Hex()
+}
+
+func respondReviewExists(ctx *gin.Context, id primitive.ObjectID) {
+	ctx.Header("Location", reviewURL(id))
+	ctx.JSON(http.StatusConflict, gin.H{
+		"error":      "You have already reviewed this book",
+		"review_id":  id.Hex(),
+		"review_url": reviewURL(id),
+	})
+}
+
+// GetMyReview returns the current user's review of a book, so the review
+// form can be filled in with it.
+func (rc *ReviewController) GetMyReview(ctx *gin.Context) {
+	bookID, err := primitive.ObjectIDFromHex(ctx.Param("bookId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized"})
+		return
+	}
+
+	var review models.Review
+	err = rc.reviewCollection.FindOne(context.TODO(), notDeleted(bson.M{"user_id": userID, "book._id": bookID})).Decode(&review)
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "You have not reviewed this book"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review"})
+		return
+	}
+
+	review.Render(false)
+	setETag(ctx, review.Version)
+	ctx.JSON(http.StatusOK, review)
+}
+
+// spoilerMode reads the spoilers query parameter: "show", the default, or
+// "hide" to redact spoilers. It writes the error response itself and
+// returns false for any other value.
+func spoilerMode(ctx *gin.Context) (bool, bool) {
+	switch ctx.DefaultQuery("spoilers", "show") {
+	case "show":
+		return false, true
+	case "hide":
+		return true, true
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid spoilers parameter"})
+		return false, false
+	}
+}
+
+// reviewSorts are the orderings offered by GetReviewsByBookID. Ties fall
+// back to the newest review first.
+var reviewSorts = map[string]bson.D{
+	"newest": {{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+	"helpful": {
+		{Key: "helpful_count", Value: -1},
+		{Key: "unhelpful_count", Value: 1},
+		{Key: "created_at", Value: -1},
+		{Key: "_id", Value: -1},
+	},
+	"rating": {{Key: "rating", Value: -1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
 }
 
 func (rc *ReviewController) GetReviewsByBookID(c *gin.Context) {
@@ -188,7 +464,7 @@ func (rc *ReviewController) GetReviewsByBookID(c *gin.Context) {
 	}
 
 	var book models.Book
-	err = rc.bookCollection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&book)
+	err = rc.bookCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objID})).Decode(&book)
 	if err != nil {
 		log.Printf("Book find error: %v", err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
@@ -197,24 +473,17 @@ func (rc *ReviewController) GetReviewsByBookID(c *gin.Context) {
 	log.Printf("Found book: %s", book.Title)
 	log.Printf("Querying reviews with bookId: %s", objID.Hex())
 
-	pipeline := []bson.M{
-		{"$match": bson.M{"book._id": objID}},
-		{
-			"$lookup": bson.M{
-				"from":         "users",
-				"localField":   "user_id",
-				"foreignField": "_id",
-				"as":           "user_info",
-			},
-		},
-		{
-			"$addFields": bson.M{
-				"username": bson.M{"$arrayElemAt": []interface{}{"$user_info.username", 0}},
-			},
-		},
+	sortOrder, ok := reviewSorts[c.DefaultQuery("sort", "newest")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort parameter"})
+		return
+	}
+	hideSpoilers, ok := spoilerMode(c)
+	if !ok {
+		return
 	}
 
-	cursor, err := rc.reviewCollection.Aggregate(context.TODO(), pipeline)
+	cursor, err := rc.reviewCollection.Find(context.TODO(), published(bson.M{"book._id": objID}), options.Find().SetSort(sortOrder))
 	log.Printf("Query filter: %+v", bson.M{"book._id": bson.M{"$eq": objID}})
 
 	if err != nil {
@@ -232,6 +501,10 @@ func (rc *ReviewController) GetReviewsByBookID(c *gin.Context) {
 	log.Printf("Found %d reviews", len(reviews))
 	log.Printf("Reviews to be sent: %+v", reviews)
 
+	for i := range reviews {
+		reviews[i].Render(hideSpoilers)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"bookTitle": book.Title,
 		"reviews":   reviews,
@@ -247,12 +520,19 @@ func (rc *ReviewController) GetReviewByID(ctx *gin.Context) {
 		return
 	}
 
+	hideSpoilers, ok := spoilerMode(ctx)
+	if !ok {
+		return
+	}
+
 	var review models.Review
-	if err := rc.reviewCollection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&review); err != nil {
+	if err := rc.reviewCollection.FindOne(context.TODO(), published(bson.M{"_id": objID})).Decode(&review); err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
 		return
 	}
 
+	review.Render(hideSpoilers)
+	setETag(ctx, review.Version)
 	ctx.JSON(http.StatusOK, review)
 }
 
@@ -265,10 +545,11 @@ func (rc *ReviewController) UpdateReview(ctx *gin.Context) {
 	}
 
 	var review models.Review
-	if err := rc.reviewCollection.FindOne(context.TODO(), bson.M{"_id": objectId}).Decode(&review); err != nil {
+	if err := rc.reviewCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objectId})).Decode(&review); err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
 		return
 	}
+	setETag(ctx, review.Version)
 	ctx.JSON(http.StatusOK, review)
 }
 
@@ -286,35 +567,65 @@ func (rc *ReviewController) EditedReview(ctx *gin.Context) {
 		return
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"rating":     updateReview.Rating,
-			"review":     updateReview.Review,
-			"updated_at": time.Now(),
-		},
+	var review models.Review
+	if err := rc.reviewCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objectId})).Decode(&review); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
 	}
 
-	result := rc.reviewCollection.FindOneAndUpdate(
-		context.TODO(),
-		bson.M{"_id": objectId},
-		update,
-		options.FindOneAndUpdate().SetReturnDocument(options.After),
-	)
+	if !checkIfMatch(ctx, review.Version) {
+		return
+	}
 
-	if result.Err() != nil {
-		log.Printf("Update failed for review ID %s: %v", id, result.Err())
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+	if errors := models.ValidateRatings(updateReview.Rating, updateReview.Ratings, review.Book.Category); len(errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"errors": errors})
 		return
 	}
 
+	decision, ok := rc.screenReview(ctx, contentfilter.Submission{UserID: review.UserID, ReviewID: review.ID, Text: updateReview.Review})
+	if !ok {
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"rating":            updateReview.Rating,
+			"contains_spoilers": updateReview.ContainsSpoilers,
+			"edited_by":         actorRole(ctx),
+			"updated_at":        time.Now(),
+		},
+		"$unset": bson.M{},
+		"$inc":   bson.M{"version": 1},
+	}
+	setSubRatings(update, updateReview.Ratings)
+	applyDecision(update, review, decision)
+
 	updatedReview := models.Review{}
-	if err := result.Decode(&updatedReview); err != nil {
-		log.Printf("Failed to decode updated review: %v", err)
+	err = database.WithTransaction(context.TODO(), rc.reviewCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		err := rc.reviewCollection.FindOneAndUpdate(
+			sessCtx,
+			versionFilter(objectId, review.Version),
+			update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updatedReview)
+		if err != nil {
+			return err
+		}
+		return database.ApplyRatingChange(sessCtx, rc.bookCollection, review.Book.ID, ratingContribution(review), ratingContribution(updatedReview))
+	})
+
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": "The resource has been modified since it was fetched"})
+		return
+	} else if err != nil {
+		log.Printf("Update failed for review ID %s: %v", id, err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Review updated successfully"})
+	updatedReview.Render(false)
+	setETag(ctx, updatedReview.Version)
+	ctx.JSON(http.StatusOK, gin.H{"message": reviewSavedMessage("Review updated successfully", updatedReview), "review": updatedReview, "actor": actorRole(ctx)})
 }
 
 func (rc *ReviewController) DeleteReviewConfirmation(ctx *gin.Context) {
@@ -327,7 +638,7 @@ func (rc *ReviewController) DeleteReviewConfirmation(ctx *gin.Context) {
 	}
 
 	var review models.Review
-	if err := rc.reviewCollection.FindOne(context.TODO(), bson.M{"_id": objectId}).Decode(&review); err != nil {
+	if err := rc.reviewCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objectId})).Decode(&review); err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
 		return
 	}
@@ -347,21 +658,157 @@ func (rc *ReviewController) DeleteReview(ctx *gin.Context) {
 	// fmt.Printf("Attempting to delete review with ID: %s\n", id)
 	// log.Printf("Received ID: %s", id)
 
-	result, err := rc.reviewCollection.DeleteOne(context.TODO(), bson.M{"_id": objectId})
-	if err != nil {
-		// fmt.Println("Error during deletion:", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized"})
 		return
 	}
 
-	log.Printf("Delete result: %+v", result)
-	if result.DeletedCount == 0 {
+	err = database.WithTransaction(context.TODO(), rc.reviewCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		_, err := rc.trashReview(sessCtx, objectId, time.Now().Truncate(time.Millisecond), userID)
+		return err
+	})
+	if err == mongo.ErrNoDocuments {
 		// fmt.Println("Review not found")
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
 		return
+	} else if err != nil {
+		// fmt.Println("Error during deletion:", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
+		return
 	}
 
 	// fmt.Printf("Delete result: %+v\n", result)
 	// fmt.Printf("Error: %v\n", err)
-	ctx.JSON(http.StatusOK, gin.H{"message": "Review deleted successfully"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "Review deleted successfully", "actor": actorRole(ctx)})
+}
+
+// trashReview moves a review and its comments to the trash and takes the
+// review out of its book's ratings. It returns the review as it was before
+// the delete. Run it inside a transaction.
+func (rc *ReviewController) trashReview(sessCtx mongo.SessionContext, id primitive.ObjectID, deletedAt time.Time, deletedBy primitive.ObjectID) (models.Review, error) {
+	var review models.Review
+	err := rc.reviewCollection.FindOneAndUpdate(
+		sessCtx,
+		notDeleted(bson.M{"_id": id}),
+		softDeleteUpdate(deletedAt, deletedBy),
+	).Decode(&review)
+	if err != nil {
+		return review, err
+	}
+
+	_, err = rc.commentCollection.UpdateMany(sessCtx, notDeleted(bson.M{"review_id": id}), softDeleteUpdate(deletedAt, deletedBy))
+	if err != nil {
+		return review, err
+	}
+	return review, database.ApplyRatingChange(sessCtx, rc.bookCollection, review.Book.ID, ratingContribution(review), database.RatingContribution{})
+}
+
+// setReviewStatus changes the status of the review matched by filter and
+// moves its contribution to the book's ratings to match. Run it inside a
+// transaction.
+func (rc *ReviewController) setReviewStatus(sessCtx mongo.SessionContext, filter bson.M, status string) (models.Review, error) {
+	update := bson.M{"$set": bson.M{"status": status}, "$inc": bson.M{"version": 1}}
+	if status == models.ReviewPublished {
+		update["$unset"] = bson.M{"flags": ""}
+	}
+
+	var previous models.Review
+	err := rc.reviewCollection.FindOneAndUpdate(sessCtx, filter, update).Decode(&previous)
+	if err != nil {
+		return previous, err
+	}
+
+	review := previous
+	review.Status = status
+	review.Version++
+	if status == models.ReviewPublished {
+		review.Flags = nil
+	}
+	return review, database.ApplyRatingChange(sessCtx, rc.bookCollection, review.Book.ID, ratingContribution(previous), ratingContribution(review))
+}
+
+func (rc *ReviewController) RestoreReview(ctx *gin.Context) {
+	id := ctx.Param("id")
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var review models.Review
+	if err := rc.reviewCollection.FindOne(context.TODO(), inTrash(bson.M{"_id": objectId})).Decode(&review); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found in trash"})
+		return
+	}
+
+	count, err := rc.bookCollection.CountDocuments(context.TODO(), notDeleted(bson.M{"_id": review.Book.ID}))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore review"})
+		return
+	}
+	if count == 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "The reviewed book is in the trash; restore the book instead"})
+		return
+	}
+
+	// As with books, only the comments removed together with the review
+	// come back.
+	var restored models.Review
+	err = database.WithTransaction(context.TODO(), rc.reviewCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		_, err := rc.commentCollection.UpdateMany(sessCtx, bson.M{"review_id": objectId, "deleted_at": review.DeletedAt}, restoreUpdate())
+		if err != nil {
+			return err
+		}
+
+		err = rc.reviewCollection.FindOneAndUpdate(
+			sessCtx,
+			inTrash(bson.M{"_id": objectId}),
+			restoreUpdate(),
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&restored)
+		if err != nil {
+			return err
+		}
+		return database.ApplyRatingChange(sessCtx, rc.bookCollection, restored.Book.ID, database.RatingContribution{}, ratingContribution(restored))
+	})
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found in trash"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore review"})
+		return
+	}
+
+	setETag(ctx, restored.Version)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Review restored successfully", "review": restored})
+}
+
+// ReviewOwner returns the ID of the user who wrote the review named by the
+// :id parameter, for use with middleware.RequireOwnerOrAdmin.
+func (rc *ReviewController) ReviewOwner(ctx *gin.Context) (string, error) {
+	objectId, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		return "", middleware.ErrResourceNotFound
+	}
+
+	var review struct {
+		UserID primitive.ObjectID `bson:"user_id"`
+	}
+	err = rc.reviewCollection.FindOne(context.TODO(), notDeleted(bson.M{"_id": objectId})).Decode(&review)
+	if err == mongo.ErrNoDocuments {
+		return "", middleware.ErrResourceNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return review.UserID.Hex(), nil
+}
+
+// ratingContribution is what a review adds to its book's aggregates, or
+// nothing when the review does not count towards them.
+func ratingContribution(review models.Review) database.RatingContribution {
+	if review.DeletedAt != nil || !review.Published() {
+		return database.RatingContribution{}
+	}
+	return database.RatingContribution{Overall: review.Rating, Dimensions: review.Ratings}
 }
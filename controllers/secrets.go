@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// newSecretToken returns a random single-use token to send to a user and
+// the hash to store in its place.
+func newSecretToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashSecretToken(token), nil
+}
+
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// appURL links to a page of the front end, which is served from the
+// allowed CORS origin.
+func appURL(path string, query url.Values) string {
+	origin := os.Getenv("PROD_ALLOWED_ORIGIN")
+	if os.Getenv("ENV") == "development" {
+		origin = os.Getenv("DEV_ALLOWED_ORIGIN")
+	}
+	return strings.TrimRight(origin, "/") + path + "?" + query.Encode()
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// notDeleted restricts a filter to documents that are not in the trash.
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": false}
+	return filter
+}
+
+func inTrash(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": true}
+	return filter
+}
+
+// softDeleteUpdate moves a document to the trash. Documents deleted together
+// share the same timestamp so that a restore can bring them back as a group.
+func softDeleteUpdate(deletedAt time.Time, deletedBy primitive.ObjectID) bson.M {
+	return bson.M{
+		"$set": bson.M{"deleted_at": deletedAt, "deleted_by": deletedBy},
+		"$inc": bson.M{"version": 1},
+	}
+}
+
+func restoreUpdate() bson.M {
+	return bson.M{
+		"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+}
@@ -1,11 +1,17 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"spa_media_review/database"
+	"spa_media_review/mailer"
 	"spa_media_review/middleware"
 	"spa_media_review/models"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,15 +19,36 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	errEmailTaken    = errors.New("email already registered")
+	errUsernameTaken = errors.New("username already taken")
+)
+
+// passwordResetTTL is how long a password reset link stays valid.
+const passwordResetTTL = time.Hour
+
+// verificationResendInterval is how long a user must wait between
+// verification emails.
+const verificationResendInterval = 5 * time.Minute
+
+// passwordResetMessage is the reply to every reset request, so that it
+// cannot be used to find out which emails have accounts.
+const passwordResetMessage = "If an account exists for that email, password reset instructions have been sent"
+
 type UserController struct {
-	userCollection *mongo.Collection
+	userCollection    *mongo.Collection
+	reviewCollection  *mongo.Collection
+	commentCollection *mongo.Collection
+	sessionCollection *mongo.Collection
+	mailer            mailer.Mailer
 }
 
-func NewUserController(collection *mongo.Collection) *UserController {
-	return &UserController{userCollection: collection}
+func NewUserController(collection, reviewCollection, commentCollection, sessionCollection *mongo.Collection, mail mailer.Mailer) *UserController {
+	return &UserController{userCollection: collection, reviewCollection: reviewCollection, commentCollection: commentCollection, sessionCollection: sessionCollection, mailer: mail}
 }
 
 func (uc *UserController) GetSignupForm(ctx *gin.Context) {
@@ -36,19 +63,13 @@ func (uc *UserController) SignupUser(ctx *gin.Context) {
 		return
 	}
 
-	count, err := uc.userCollection.CountDocuments(ctx, bson.M{"email": user.Email})
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-		return
-	}
-	if count > 0 {
-		ctx.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
-		return
-	}
-
+	user.Email = models.NormalizeEmail(user.Email)
+	now := time.Now()
 	user.ID = primitive.NewObjectID()
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.EmailVerified = false
+	user.VerificationSentAt = &now
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -57,23 +78,156 @@ func (uc *UserController) SignupUser(ctx *gin.Context) {
 	}
 	user.Password = string(hash)
 
-	_, err = uc.userCollection.InsertOne(ctx, user)
-	if err != nil {
+	// The unique index on email backs up the check for concurrent signups.
+	err = database.WithTransaction(ctx, uc.userCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		count, err := uc.userCollection.CountDocuments(sessCtx, bson.M{"email": user.Email})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errEmailTaken
+		}
+
+		_, err = uc.userCollection.InsertOne(sessCtx, user)
+		return err
+	})
+	if err == errEmailTaken || mongo.IsDuplicateKeyError(err) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to create user: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
+	go uc.sendVerificationEmail(user)
+
 	ctx.JSON(http.StatusCreated, gin.H{
-		"message": "User created successfully",
+		"message": "User created successfully, check your email to verify your address",
 		"user": gin.H{
-			"_id":      user.ID.Hex(),
-			"email":    user.Email,
-			"username": user.Username,
-			"isAdmin":  user.IsAdmin,
+			"_id":            user.ID.Hex(),
+			"email":          user.Email,
+			"username":       user.Username,
+			"isAdmin":        user.IsAdmin,
+			"email_verified": user.EmailVerified,
 		},
 	})
 }
 
+func (uc *UserController) sendVerificationEmail(user models.User) {
+	token, err := middleware.GenerateEmailVerificationToken(user)
+	if err != nil {
+		log.Printf("Failed to generate verification token for user %s: %v", user.ID.Hex(), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	link := appURL("/verify-email", url.Values{"token": {token}})
+	err = uc.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"Thanks for signing up. Please confirm your email address by opening this link within the next 24 hours:\n\n" +
+			link + "\n\n" +
+			"If you did not create an account, you can ignore this email.\n",
+	})
+	if err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID.Hex(), err)
+	}
+}
+
+// VerifyEmail confirms a user's email address with the token from their
+// verification email. The token is tied to the address it was sent to, so
+// it stops working if the user changes their email.
+func (uc *UserController) VerifyEmail(ctx *gin.Context) {
+	claims, err := middleware.ParseEmailVerificationToken(ctx.Query("token"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	result, err := uc.userCollection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID, "email": claims.Email},
+		bson.M{"$set": bson.M{"email_verified": true, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Failed to verify email for user %s: %v", userID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerification sends the current user a new verification email, at
+// most once every verificationResendInterval.
+func (uc *UserController) ResendVerification(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	now := time.Now()
+	var user models.User
+	err := uc.userCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"_id":            userID,
+			"email_verified": bson.M{"$ne": true},
+			"$or": bson.A{
+				bson.M{"verification_sent_at": bson.M{"$exists": false}},
+				bson.M{"verification_sent_at": bson.M{"$lte": now.Add(-verificationResendInterval)}},
+			},
+		},
+		bson.M{"$set": bson.M{"verification_sent_at": now}},
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		uc.respondResendRefused(ctx, userID, now)
+		return
+	} else if err != nil {
+		log.Printf("Failed to resend verification to user %s: %v", userID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	go uc.sendVerificationEmail(user)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// respondResendRefused explains why ResendVerification did not send an
+// email: the user is already verified or asked too recently.
+func (uc *UserController) respondResendRefused(ctx *gin.Context, userID primitive.ObjectID, now time.Time) {
+	var user models.User
+	if err := uc.userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.EmailVerified {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Email already verified"})
+		return
+	}
+
+	wait := verificationResendInterval
+	if user.VerificationSentAt != nil {
+		wait = user.VerificationSentAt.Add(verificationResendInterval).Sub(now)
+	}
+	ctx.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "A verification email was sent recently, please try again later"})
+}
+
 func (uc *UserController) GetLoginForm(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "Login form", "user": nil})
 }
@@ -90,7 +244,7 @@ func (uc *UserController) LoginUser(ctx *gin.Context) {
 	}
 
 	var user models.User
-	err := uc.userCollection.FindOne(ctx, bson.M{"email": loginRequest.Email}).Decode(&user)
+	err := uc.userCollection.FindOne(ctx, bson.M{"email": models.NormalizeEmail(loginRequest.Email)}).Decode(&user)
 	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
@@ -102,52 +256,76 @@ func (uc *UserController) LoginUser(ctx *gin.Context) {
 		return
 	}
 
-	accessToken, err := middleware.GenerateToken(user)
+	if user.BannedAt != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "This account has been banned"})
+		return
+	}
+
+	tokens, err := middleware.StartSession(ctx, user)
 	if err != nil {
+		log.Printf("Failed to start session for user %s: %v", user.ID.Hex(), err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
 		return
 	}
 
-	refreshToken, err := middleware.GenerateRefreshToken(user)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate refresh token"})
+	if err := middleware.SetAuthCookies(ctx, tokens); err != nil {
+		log.Printf("Failed to set auth cookies: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not set auth cookies"})
 		return
 	}
 
-	domain, secure, httpOnly, err := middleware.GetCookieSettings()
-	if err != nil {
-		log.Fatalf("Failed to parse environment variables: %v", err)
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"user": gin.H{
+			"_id":            user.ID.Hex(),
+			"email":          user.Email,
+			"username":       user.Username,
+			"isAdmin":        user.IsAdmin,
+			"email_verified": user.EmailVerified,
+		},
+	})
+}
+
+// RefreshTokens swaps the refresh token cookie for a new access token and
+// refresh token. Each refresh token works once: using one again after it
+// has been replaced signs the session out.
+func (uc *UserController) RefreshTokens(ctx *gin.Context) {
+	refreshToken, err := ctx.Cookie("refresh_token")
+	if err != nil || refreshToken == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token not provided"})
+		return
 	}
 
-	ctx.SetSameSite(http.SameSiteLaxMode)
-	ctx.SetCookie(
-		"access_token",
-		accessToken,
-		3600*24,
-		"/",
-		domain,
-		secure,
-		httpOnly,
-	)
+	user, tokens, err := middleware.RefreshSession(ctx, refreshToken)
+	switch {
+	case err == middleware.ErrInvalidRefreshToken:
+		clearAuthCookies(ctx)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	case err == database.ErrRefreshTokenReused:
+		clearAuthCookies(ctx)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used, please log in again"})
+		return
+	case err != nil:
+		log.Printf("Failed to refresh session: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		return
+	}
 
-	ctx.SetSameSite(http.SameSiteLaxMode)
-	ctx.SetCookie(
-		"refresh_token",
-		refreshToken,
-		3600*24,
-		"/",
-		domain,
-		secure,
-		httpOnly,
-	)
+	if err := middleware.SetAuthCookies(ctx, tokens); err != nil {
+		log.Printf("Failed to set auth cookies: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not set auth cookies"})
+		return
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
+		"message": "Tokens refreshed",
 		"user": gin.H{
-			"_id":      user.ID.Hex(),
-			"email":    user.Email,
-			"username": user.Username,
-			"isAdmin":  user.IsAdmin,
+			"_id":            user.ID.Hex(),
+			"email":          user.Email,
+			"username":       user.Username,
+			"isAdmin":        user.IsAdmin,
+			"email_verified": user.EmailVerified,
 		},
 	})
 }
@@ -166,54 +344,342 @@ func (uc *UserController) ResetPassword(ctx *gin.Context) {
 		return
 	}
 
-	env := os.Getenv("ENV")
+	clearAuthCookies(ctx)
 
-	var domain string
-	if env == "development" {
-		domain = strings.Split(os.Getenv("DEV_ALLOWED_ORIGIN"), "//")[1]
-	} else {
-		domain = strings.Split(os.Getenv("PROD_ALLOWED_ORIGIN"), "//")[1]
+	token, hash, err := newSecretToken()
+	if err != nil {
+		log.Printf("Failed to generate password reset token: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
 	}
 
-	ctx.SetSameSite(http.SameSiteLaxMode)
-	ctx.SetCookie(
-		"access_token",
-		"",
-		-1,
-		"/",
-		domain,
-		false,
-		false,
-	)
+	var user models.User
+	err = uc.userCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"email": models.NormalizeEmail(input.Email)},
+		bson.M{
+			"$set":   bson.M{"password_reset_hash": hash, "password_reset_expires_at": time.Now().Add(passwordResetTTL)},
+			"$unset": bson.M{"passwordResetToken": ""},
+		},
+	).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Printf("Failed to store password reset token: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
 
-	ctx.SetSameSite(http.SameSiteLaxMode)
-	ctx.SetCookie(
-		"refresh_token",
-		"",
-		-1,
-		"/",
-		domain,
-		false,
-		false,
-	)
+	if err == nil {
+		// Sent in the background so that the response takes as long for
+		// unknown emails as for known ones.
+		go uc.sendPasswordReset(user, token)
+	}
 
-	result := uc.userCollection.FindOneAndUpdate(
+	ctx.JSON(http.StatusOK, gin.H{"message": passwordResetMessage})
+}
+
+func (uc *UserController) sendPasswordReset(user models.User, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	link := appURL("/reset-password", url.Values{"token": {token}})
+	err := uc.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"Someone asked to reset the password for your account. To choose a new password, open this link within the next hour:\n\n" +
+			link + "\n\n" +
+			"If you did not ask for this, you can ignore this email and your password will stay the same.\n",
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email to user %s: %v", user.ID.Hex(), err)
+	}
+}
+
+// ConfirmPasswordReset sets a new password using the token from a reset
+// email. The token can only be used once, and every existing session of
+// the user is signed out.
+func (uc *UserController) ConfirmPasswordReset(ctx *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	if message := models.ValidatePassword(input.Password); message != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"errors": gin.H{"password": message}})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// Tokens carry their issue time in whole seconds.
+	now := time.Now().Truncate(time.Second)
+	var user models.User
+	err = uc.userCollection.FindOneAndUpdate(
 		ctx,
-		bson.M{"email": input.Email},
-		bson.M{"$set": bson.M{"passwordResetToken": "token"}},
-	)
+		bson.M{
+			"password_reset_hash":       hashSecretToken(input.Token),
+			"password_reset_expires_at": bson.M{"$gt": now},
+		},
+		bson.M{
+			"$set":   bson.M{"password": string(hash), "tokens_valid_after": now, "updated_at": now},
+			"$unset": bson.M{"password_reset_hash": "", "password_reset_expires_at": ""},
+		},
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to reset password: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if _, err := database.RevokeSessions(ctx, uc.sessionCollection, bson.M{"user_id": user.ID}, models.SessionRevokedPasswordReset); err != nil {
+		log.Printf("Failed to revoke sessions of user %s: %v", user.ID.Hex(), err)
+	}
+
+	clearAuthCookies(ctx)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please log in again"})
+}
 
-	if result.Err() != nil {
+// UpdateProfile changes the current user's username and carries the new
+// name over to the reviews and comments they have written.
+func (uc *UserController) UpdateProfile(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input struct {
+		Username string `json:"username" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	username := models.NormalizeUsername(input.Username)
+	if message := models.ValidateUsername(username); message != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	var user models.User
+	err := database.WithTransaction(ctx, uc.userCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		count, err := uc.userCollection.CountDocuments(sessCtx, bson.M{"username": username, "_id": bson.M{"$ne": userID}})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errUsernameTaken
+		}
+
+		err = uc.userCollection.FindOneAndUpdate(
+			sessCtx,
+			bson.M{"_id": userID},
+			bson.M{"$set": bson.M{"username": username, "updated_at": time.Now()}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&user)
+		if err != nil {
+			return err
+		}
+
+		if _, err := database.PropagateUsername(sessCtx, uc.reviewCollection, user.Public()); err != nil {
+			return err
+		}
+		_, err = database.PropagateUsername(sessCtx, uc.commentCollection, user.Public())
+		return err
+	})
+	switch {
+	case err == errUsernameTaken:
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		return
+	case err == mongo.ErrNoDocuments:
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
+	case err != nil:
+		log.Printf("Failed to update user %s: %v", userID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Password reset instructions sent"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully", "user": user.Public()})
 }
 
 func (uc *UserController) LogoutUser(ctx *gin.Context) {
 	log.Println("LogoutUser endpoint hit")
 
+	userID, _ := currentUserID(ctx)
+	if sessionID, err := primitive.ObjectIDFromHex(ctx.GetString("sessionID")); err == nil {
+		_, err := database.RevokeSessions(ctx, uc.sessionCollection, bson.M{"_id": sessionID, "user_id": userID}, models.SessionRevokedLogout)
+		if err != nil {
+			log.Printf("Failed to revoke session %s: %v", sessionID.Hex(), err)
+		}
+	}
+	if err := revokeCurrentToken(ctx); err != nil {
+		log.Printf("Failed to revoke access token: %v", err)
+	}
+
+	clearAuthCookies(ctx)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAll signs the current user out of every session, on every device.
+func (uc *UserController) LogoutAll(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := database.RevokeUserTokens(ctx, uc.userCollection, uc.sessionCollection, userID, models.SessionRevokedLogoutAll); err != nil {
+		log.Printf("Failed to revoke tokens of user %s: %v", userID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	// Tokens issued in the current second outlive tokens_valid_after.
+	if err := revokeCurrentToken(ctx); err != nil {
+		log.Printf("Failed to revoke access token: %v", err)
+	}
+
+	clearAuthCookies(ctx)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Logged out everywhere"})
+}
+
+// GetSessions lists the devices the current user is signed in on.
+func (uc *UserController) GetSessions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	sessions, err := database.ListSessions(ctx, uc.sessionCollection, userID)
+	if err != nil {
+		log.Printf("Failed to list sessions of user %s: %v", userID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID.Hex() == ctx.GetString("sessionID")
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession signs the current user out of one of their sessions. Revoking
+// the session making the request logs the user out.
+func (uc *UserController) RevokeSession(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	sessionID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	revoked, err := database.RevokeSessions(ctx, uc.sessionCollection, bson.M{"_id": sessionID, "user_id": userID}, models.SessionRevokedByUser)
+	if err != nil {
+		log.Printf("Failed to revoke session %s: %v", sessionID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	if revoked == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if sessionID.Hex() == ctx.GetString("sessionID") {
+		if err := revokeCurrentToken(ctx); err != nil {
+			log.Printf("Failed to revoke access token: %v", err)
+		}
+		clearAuthCookies(ctx)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// ChangePassword replaces the current user's password. Every other session
+// is signed out, and the caller gets the tokens of a new one.
+func (uc *UserController) ChangePassword(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := uc.userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.CurrentPassword)); err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if message := models.ValidatePassword(input.NewPassword); message != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"errors": gin.H{"new_password": message}})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	_, err = uc.userCollection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"password": string(hash), "updated_at": time.Now()}})
+	if err == nil {
+		err = database.RevokeUserTokens(ctx, uc.userCollection, uc.sessionCollection, userID, models.SessionRevokedPasswordChange)
+	}
+	if err != nil {
+		log.Printf("Failed to change password of user %s: %v", userID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	if err := revokeCurrentToken(ctx); err != nil {
+		log.Printf("Failed to revoke access token: %v", err)
+	}
+
+	tokens, err := middleware.StartSession(ctx, user)
+	if err != nil {
+		log.Printf("Failed to start session for user %s: %v", userID.Hex(), err)
+		clearAuthCookies(ctx)
+		ctx.JSON(http.StatusOK, gin.H{"message": "Password changed, please log in again"})
+		return
+	}
+	if err := middleware.SetAuthCookies(ctx, tokens); err != nil {
+		log.Printf("Failed to set auth cookies: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed, but you could not be signed back in; please log in again"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password changed, you have been signed out on other devices"})
+}
+
+func clearAuthCookies(ctx *gin.Context) {
 	env := os.Getenv("ENV")
 
 	var domain string
@@ -244,6 +710,4 @@ func (uc *UserController) LogoutUser(ctx *gin.Context) {
 		false,
 		false,
 	)
-
-	ctx.JSON(http.StatusOK, gin.H{"message": "Logged out"})
 }
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"spa_media_review/database"
+	"spa_media_review/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VoteReview records the current user's helpful or not helpful vote on a
+// review, replacing any vote they cast before.
+func (rc *ReviewController) VoteReview(ctx *gin.Context) {
+	var input struct {
+		Helpful *bool `json:"helpful" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: helpful must be true or false"})
+		return
+	}
+
+	review, userID, ok := rc.votableReview(ctx)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	vote := models.ReviewVote{ID: primitive.NewObjectID(), ReviewID: review.ID, UserID: userID, Helpful: *input.Helpful, UpdatedAt: now}
+
+	err := database.WithTransaction(context.TODO(), rc.reviewCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		var previous models.ReviewVote
+		err := rc.voteCollection.FindOneAndUpdate(
+			sessCtx,
+			bson.M{"review_id": review.ID, "user_id": userID},
+			bson.M{
+				"$set":         bson.M{"helpful": vote.Helpful, "updated_at": now},
+				"$setOnInsert": bson.M{"_id": vote.ID, "created_at": now},
+			},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+		).Decode(&previous)
+
+		inc := bson.M{}
+		switch {
+		case err == mongo.ErrNoDocuments:
+			vote.CreatedAt = now
+			inc[vote.VoteCounter()] = 1
+		case err != nil:
+			return err
+		case previous.Helpful != vote.Helpful:
+			vote.ID, vote.CreatedAt = previous.ID, previous.CreatedAt
+			inc[previous.VoteCounter()] = -1
+			inc[vote.VoteCounter()] = 1
+		default:
+			vote.ID, vote.CreatedAt = previous.ID, previous.CreatedAt
+			return nil
+		}
+
+		_, err = rc.reviewCollection.UpdateOne(sessCtx, bson.M{"_id": review.ID}, bson.M{"$inc": inc})
+		return err
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Your vote was changed by another request, please try again"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to record vote on review %s: %v", review.ID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+
+	rc.respondVoteCounts(ctx, review.ID, gin.H{"message": "Vote recorded", "vote": vote})
+}
+
+// DeleteVote withdraws the current user's vote on a review.
+func (rc *ReviewController) DeleteVote(ctx *gin.Context) {
+	review, userID, ok := rc.votableReview(ctx)
+	if !ok {
+		return
+	}
+
+	err := database.WithTransaction(context.TODO(), rc.reviewCollection.Database().Client(), func(sessCtx mongo.SessionContext) error {
+		var vote models.ReviewVote
+		err := rc.voteCollection.FindOneAndDelete(sessCtx, bson.M{"review_id": review.ID, "user_id": userID}).Decode(&vote)
+		if err != nil {
+			return err
+		}
+		_, err = rc.reviewCollection.UpdateOne(sessCtx, bson.M{"_id": review.ID}, bson.M{"$inc": bson.M{vote.VoteCounter(): -1}})
+		return err
+	})
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "You have not voted on this review"})
+		return
+	} else if err != nil {
+		log.Printf("Failed to remove vote on review %s: %v", review.ID.Hex(), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove vote"})
+		return
+	}
+
+	rc.respondVoteCounts(ctx, review.ID, gin.H{"message": "Vote removed"})
+}
+
+// votableReview loads the review named by :id and checks that the current
+// user may vote on it. It writes the error response itself and returns false
+// on failure.
+func (rc *ReviewController) votableReview(ctx *gin.Context) (models.Review, primitive.ObjectID, bool) {
+	var review models.Review
+
+	reviewID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return review, primitive.NilObjectID, false
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized"})
+		return review, primitive.NilObjectID, false
+	}
+
+	if err := rc.reviewCollection.FindOne(context.TODO(), published(bson.M{"_id": reviewID})).Decode(&review); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return review, primitive.NilObjectID, false
+	}
+
+	if review.UserID == userID {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You cannot vote on your own review"})
+		return review, primitive.NilObjectID, false
+	}
+	return review, userID, true
+}
+
+func (rc *ReviewController) respondVoteCounts(ctx *gin.Context, reviewID primitive.ObjectID, body gin.H) {
+	var counts struct {
+		Helpful   int64 `bson:"helpful_count"`
+		Unhelpful int64 `bson:"unhelpful_count"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"helpful_count": 1, "unhelpful_count": 1})
+	if err := rc.reviewCollection.FindOne(context.TODO(), bson.M{"_id": reviewID}, opts).Decode(&counts); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vote counts"})
+		return
+	}
+
+	body["helpful_count"] = counts.Helpful
+	body["unhelpful_count"] = counts.Unhelpful
+	ctx.JSON(http.StatusOK, body)
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReviewUserBookIndex is the unique index that allows one review per user
+// and book. Databases holding duplicates need `cmd/admin dedupe-reviews`
+// before it can be built.
+const ReviewUserBookIndex = "reviews_user_book_unique"
+
+// UserEmailIndex is the unique index on email addresses. Databases where two
+// accounts share an address need `cmd/admin normalize-emails`, which lists
+// them, and the accounts merged or removed before it can be built.
+const UserEmailIndex = "users_email_unique"
+
+type collectionIndex struct {
+	collection string
+	model      mongo.IndexModel
+}
+
+// IndexError lists the indexes EnsureIndexes could not create.
+type IndexError struct {
+	Failed map[string]error
+}
+
+func (e *IndexError) Error() string {
+	names := make([]string, 0, len(e.Failed))
+	for name, err := range e.Failed {
+		names = append(names, fmt.Sprintf("%s (%v)", name, err))
+	}
+	sort.Strings(names)
+	return "failed to create indexes: " + strings.Join(names, "; ")
+}
+
+// Missing reports whether the named index could not be created.
+func (e *IndexError) Missing(name string) bool {
+	_, ok := e.Failed[name]
+	return ok
+}
+
+func appIndexes() []collectionIndex {
+	return []collectionIndex{
+		{"books", mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "title", Value: "text"},
+				{Key: "author", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "category", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("books_text").
+				SetDefaultLanguage("english").
+				SetWeights(bson.D{
+					{Key: "title", Value: 10},
+					{Key: "author", Value: 5},
+					{Key: "category", Value: 2},
+					{Key: "description", Value: 1},
+				}),
+		}},
+		{"books", mongo.IndexModel{
+			Keys: bson.D{{Key: "isbn13", Value: 1}},
+			Options: options.Index().
+				SetName("books_isbn13_unique").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"isbn13": bson.M{"$type": "string"}}),
+		}},
+		{"users", mongo.IndexModel{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName(UserEmailIndex).SetUnique(true),
+		}},
+		{"users", mongo.IndexModel{
+			Keys:    bson.D{{Key: "password_reset_hash", Value: 1}},
+			Options: options.Index().SetName("users_password_reset_hash").SetSparse(true),
+		}},
+		// Deleted reviews are included so that a user who removes a review and
+		// writes another gets their old document back rather than a second one.
+		{"reviews", mongo.IndexModel{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "book._id", Value: 1}},
+			Options: options.Index().SetName(ReviewUserBookIndex).SetUnique(true),
+		}},
+		{"reviews", mongo.IndexModel{
+			Keys:    bson.D{{Key: "text_hash", Value: 1}},
+			Options: options.Index().SetName("reviews_text_hash").SetSparse(true),
+		}},
+		{"reviews", mongo.IndexModel{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("reviews_user_created"),
+		}},
+		{"reviews", mongo.IndexModel{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("reviews_status_created").SetSparse(true),
+		}},
+		{"review_votes", mongo.IndexModel{
+			Keys:    bson.D{{Key: "review_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("review_votes_review_user_unique").SetUnique(true),
+		}},
+		{"comments", mongo.IndexModel{
+			Keys:    bson.D{{Key: "review_id", Value: 1}, {Key: "parent_id", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("comments_review_parent"),
+		}},
+		{"comments", mongo.IndexModel{
+			Keys:    bson.D{{Key: "ancestors", Value: 1}},
+			Options: options.Index().SetName("comments_ancestors"),
+		}},
+		{"comments", mongo.IndexModel{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("comments_user"),
+		}},
+		{"review_reports", mongo.IndexModel{
+			Keys:    bson.D{{Key: "review_id", Value: 1}, {Key: "reporter_id", Value: 1}},
+			Options: options.Index().SetName("review_reports_review_reporter_unique").SetUnique(true),
+		}},
+		{"review_reports", mongo.IndexModel{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "review_id", Value: 1}},
+			Options: options.Index().SetName("review_reports_status_review"),
+		}},
+		{"sessions", mongo.IndexModel{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("sessions_user"),
+		}},
+		// Sessions and revoked token IDs are dropped by MongoDB once they
+		// expire.
+		{"sessions", mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("sessions_expires_ttl").SetExpireAfterSeconds(0),
+		}},
+		{"revoked_tokens", mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("revoked_tokens_expires_ttl").SetExpireAfterSeconds(0),
+		}},
+	}
+}
+
+// EnsureIndexes creates the application's indexes. Each index is created on
+// its own, so one that cannot be built does not stop the rest; the ones that
+// failed are listed in the returned *IndexError.
+func EnsureIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	failed := map[string]error{}
+	for _, index := range appIndexes() {
+		if _, err := db.Collection(index.collection).Indexes().CreateOne(ctx, index.model); err != nil {
+			failed[*index.model.Options.Name] = err
+		}
+	}
+	if len(failed) > 0 {
+		return &IndexError{Failed: failed}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"spa_media_review/imaging"
+	"spa_media_review/models"
+	"spa_media_review/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MigrateBookImages moves base64 covers stored in the legacy "image" field
+// into the blob store, generating the same variants as a fresh upload.
+func MigrateBookImages(ctx context.Context, books *mongo.Collection, store storage.BlobStore) (int, error) {
+	cursor, err := books.Find(ctx, bson.M{"image": bson.M{"$type": "string", "$ne": ""}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query books: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var legacy struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Image string             `bson:"image"`
+		}
+		if err := cursor.Decode(&legacy); err != nil {
+			return migrated, err
+		}
+
+		data, err := base64.StdEncoding.DecodeString(legacy.Image)
+		if err != nil {
+			log.Printf("Skipping book %s: invalid base64 image: %v", legacy.ID.Hex(), err)
+			continue
+		}
+
+		images, err := imaging.SaveCover(ctx, store, legacy.ID, data)
+		if errors.Is(err, imaging.ErrUnsupportedFormat) || errors.Is(err, imaging.ErrTooLarge) {
+			log.Printf("Skipping book %s: %v", legacy.ID.Hex(), err)
+			continue
+		} else if err != nil {
+			return migrated, fmt.Errorf("failed to store cover for book %s: %v", legacy.ID.Hex(), err)
+		}
+
+		_, err = books.UpdateOne(ctx, bson.M{"_id": legacy.ID}, bson.M{
+			"$set":   bson.M{"image_key": images["full"].Key, "images": images},
+			"$unset": bson.M{"image": ""},
+		})
+		if err != nil {
+			return migrated, fmt.Errorf("failed to update book %s: %v", legacy.ID.Hex(), err)
+		}
+		migrated++
+	}
+
+	return migrated, cursor.Err()
+}
+
+// StripReviewCopies replaces the full book and user documents that older
+// reviews embedded (including password hashes) with the reference and
+// snapshot fields that reviews now store.
+func StripReviewCopies(ctx context.Context, reviews *mongo.Collection) (int64, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"user": bson.M{"$exists": true}},
+		{"book.description": bson.M{"$exists": true}},
+	}}
+
+	result, err := reviews.UpdateMany(ctx, filter, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"book": bson.M{
+				"_id":      "$book._id",
+				"title":    "$book.title",
+				"author":   "$book.author",
+				"category": "$book.category",
+			},
+			"username": bson.M{"$ifNull": bson.A{"$username", "$user.username"}},
+		}}},
+		{{Key: "$unset", Value: "user"}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to strip embedded review copies: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// DedupeReviews removes extra reviews written by the same user for the same
+// book so that the unique (user, book) index can be built. It keeps the
+// user's live review if there is one, preferring the most recently written,
+// and returns the number of reviews removed. Run RecomputeRatings afterwards.
+func DedupeReviews(ctx context.Context, reviews *mongo.Collection) (int64, error) {
+	cursor, err := reviews.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$addFields", Value: bson.M{"_live": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$deleted_at", nil}}, 0, 1,
+		}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_live", Value: -1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"user": "$user_id", "book": "$book._id"},
+			"ids": bson.M{"$push": "$_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var groups []struct {
+		IDs []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	for _, group := range groups {
+		result, err := reviews.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": group.IDs[1:]}})
+		if err != nil {
+			return removed, err
+		}
+		removed += result.DeletedCount
+	}
+	return removed, nil
+}
+
+// EmailConflict is a set of accounts whose email addresses are the same
+// once normalized.
+type EmailConflict struct {
+	Email   string               `bson:"_id"`
+	UserIDs []primitive.ObjectID `bson:"ids"`
+}
+
+// NormalizeEmails stores every user's email in the form produced by
+// models.NormalizeEmail. Accounts whose addresses clash are left alone and
+// returned, oldest first, to be merged or removed by hand. It returns the
+// number of addresses rewritten.
+func NormalizeEmails(ctx context.Context, users *mongo.Collection) (int64, []EmailConflict, error) {
+	cursor, err := users.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}},
+			"ids":    bson.M{"$push": "$_id"},
+			"emails": bson.M{"$push": "$email"},
+		}}},
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var groups []struct {
+		EmailConflict `bson:",inline"`
+		Emails        []string `bson:"emails"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return 0, nil, err
+	}
+
+	var normalized int64
+	var conflicts []EmailConflict
+	for _, group := range groups {
+		if group.Email == "" {
+			continue
+		}
+		if len(group.UserIDs) > 1 {
+			conflicts = append(conflicts, group.EmailConflict)
+			continue
+		}
+		email := models.NormalizeEmail(group.Emails[0])
+		if email == group.Emails[0] {
+			continue
+		}
+		result, err := users.UpdateOne(ctx, bson.M{"_id": group.UserIDs[0]}, bson.M{"$set": bson.M{"email": email}})
+		if err != nil {
+			return normalized, conflicts, err
+		}
+		normalized += result.ModifiedCount
+	}
+	return normalized, conflicts, nil
+}
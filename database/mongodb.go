@@ -20,6 +20,10 @@ var DB *mongo.Database
 var BookCollection *mongo.Collection
 var ReviewCollection *mongo.Collection
 var UserCollection *mongo.Collection
+var VoteCollection *mongo.Collection
+var CommentCollection *mongo.Collection
+var ReportCollection *mongo.Collection
+var SessionCollection *mongo.Collection
 
 func Connect_to_mongodb() error {
 
@@ -40,6 +44,10 @@ func Connect_to_mongodb() error {
 	BookCollection = DB.Collection("books")
 	ReviewCollection = DB.Collection("reviews")
 	UserCollection = DB.Collection("users")
+	VoteCollection = DB.Collection("review_votes")
+	CommentCollection = DB.Collection("comments")
+	ReportCollection = DB.Collection("review_reports")
+	SessionCollection = DB.Collection("sessions")
 
 	fmt.Println("Connected to MongoDB.")
 	return nil
@@ -57,13 +65,14 @@ func SetupAdminUser(db *mongo.Database) error {
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(os.Getenv("ADMIN_PASSWORD")), bcrypt.DefaultCost)
 
 	admin := models.User{
-		ID:        primitive.NewObjectID(),
-		Username:  "admin",
-		Email:     "admin@admin.com",
-		Password:  string(hashedPassword),
-		IsAdmin:   true,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:            primitive.NewObjectID(),
+		Username:      "admin",
+		Email:         "admin@admin.com",
+		Password:      string(hashedPassword),
+		IsAdmin:       true,
+		EmailVerified: true,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
 	}
 
 	_, err := collection.UpdateOne(
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"spa_media_review/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PropagateBookSummary rewrites the book snapshot on every review of the
+// book whose copy no longer matches. It returns the number of reviews fixed.
+func PropagateBookSummary(ctx context.Context, reviews *mongo.Collection, book models.BookSummary) (int64, error) {
+	filter := bson.M{
+		"book._id": book.ID,
+		"$or": []bson.M{
+			{"book.title": bson.M{"$ne": book.Title}},
+			{"book.author": bson.M{"$ne": book.Author}},
+			{"book.category": bson.M{"$ne": book.Category}},
+		},
+	}
+	result, err := reviews.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"book": book}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// PropagateUsername rewrites the username snapshot on every review or
+// comment in the collection written by the user whose copy no longer
+// matches. It returns the number of documents fixed.
+func PropagateUsername(ctx context.Context, collection *mongo.Collection, user models.PublicUser) (int64, error) {
+	filter := bson.M{"user_id": user.ID, "username": bson.M{"$ne": user.Username}}
+	result, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"username": user.Username}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+type ReconcileReport struct {
+	BookSnapshots    int64
+	Usernames        int64
+	CommentUsernames int64
+}
+
+// ReconcileSnapshots walks every book and user and repairs the denormalized
+// copies of them held by reviews and comments.
+func ReconcileSnapshots(ctx context.Context, books, users, reviews, comments *mongo.Collection) (ReconcileReport, error) {
+	var report ReconcileReport
+
+	bookCursor, err := books.Find(ctx, bson.M{})
+	if err != nil {
+		return report, err
+	}
+	defer bookCursor.Close(ctx)
+
+	for bookCursor.Next(ctx) {
+		var book models.Book
+		if err := bookCursor.Decode(&book); err != nil {
+			return report, err
+		}
+		fixed, err := PropagateBookSummary(ctx, reviews, book.Summary())
+		if err != nil {
+			return report, err
+		}
+		report.BookSnapshots += fixed
+	}
+	if err := bookCursor.Err(); err != nil {
+		return report, err
+	}
+
+	userCursor, err := users.Find(ctx, bson.M{})
+	if err != nil {
+		return report, err
+	}
+	defer userCursor.Close(ctx)
+
+	for userCursor.Next(ctx) {
+		var user models.User
+		if err := userCursor.Decode(&user); err != nil {
+			return report, err
+		}
+		fixed, err := PropagateUsername(ctx, reviews, user.Public())
+		if err != nil {
+			return report, err
+		}
+		report.Usernames += fixed
+
+		fixed, err = PropagateUsername(ctx, comments, user.Public())
+		if err != nil {
+			return report, err
+		}
+		report.CommentUsernames += fixed
+	}
+	return report, userCursor.Err()
+}
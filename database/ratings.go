@@ -0,0 +1,224 @@
+package database
+
+import (
+	"context"
+	"math"
+	"spa_media_review/models"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RatingContribution is what a single review adds to its book's rating
+// aggregates: the overall rating and any sub-ratings. The zero value stands
+// for a review that does not count.
+type RatingContribution struct {
+	Overall    float64
+	Dimensions map[string]float64
+}
+
+// ApplyRatingChange moves a single review's contribution to its book's
+// rating aggregates from old to new, so zero -> 4 adds a review and
+// 4 -> zero removes one. Run it in the same transaction as the review write.
+func ApplyRatingChange(ctx context.Context, books *mongo.Collection, bookID primitive.ObjectID, old, new RatingContribution) error {
+	counts := map[string]int64{}
+	sums := map[string]float64{}
+	touched := map[string]bool{}
+
+	apply := func(c RatingContribution, sign int64) {
+		if c.Overall == 0 {
+			return
+		}
+		counts["rating_count"] += sign
+		counts["rating_histogram."+histogramBucket(c.Overall)] += sign
+		sums["rating_sum"] += float64(sign) * c.Overall
+		for name, value := range c.Dimensions {
+			counts["dimension_ratings."+name+".count"] += sign
+			sums["dimension_ratings."+name+".sum"] += float64(sign) * value
+			touched[name] = true
+		}
+	}
+	apply(old, -1)
+	apply(new, 1)
+
+	inc := bson.M{}
+	for key, delta := range counts {
+		if delta != 0 {
+			inc[key] = delta
+		}
+	}
+	for key, delta := range sums {
+		if delta != 0 {
+			inc[key] = delta
+		}
+	}
+	if len(inc) == 0 {
+		return nil
+	}
+
+	if _, err := books.UpdateOne(ctx, bson.M{"_id": bookID}, bson.M{"$inc": inc}); err != nil {
+		return err
+	}
+
+	averages := bson.M{"rating_avg": averageExpr("$rating_sum", "$rating_count")}
+	for name := range touched {
+		prefix := "$dimension_ratings." + name
+		averages["dimension_ratings."+name+".avg"] = averageExpr(prefix+".sum", prefix+".count")
+	}
+	_, err := books.UpdateOne(ctx, bson.M{"_id": bookID}, mongo.Pipeline{
+		{{Key: "$set", Value: averages}},
+	})
+	return err
+}
+
+func averageExpr(sum, count string) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$gt": bson.A{count, 0}},
+		bson.M{"$divide": bson.A{sum, count}},
+		0,
+	}}
+}
+
+// histogramBucket is the whole-star bucket a rating is counted in, so that
+// 4 and 4.5 both count as four stars.
+func histogramBucket(rating float64) string {
+	return strconv.Itoa(int(math.Floor(rating)))
+}
+
+// RecomputeRatings rebuilds the rating aggregates of every book that is not
+// in the trash from its published reviews, correcting any drift in the
+// maintained counters. It returns the number of books updated.
+func RecomputeRatings(ctx context.Context, books, reviews *mongo.Collection) (int, error) {
+	counted := bson.D{{Key: "$match", Value: bson.M{
+		"deleted_at": bson.M{"$exists": false},
+		"status":     bson.M{"$nin": models.UnpublishedReviewStatuses},
+	}}}
+
+	cursor, err := reviews.Aggregate(ctx, mongo.Pipeline{
+		counted,
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"book": "$book._id", "bucket": bson.M{"$floor": "$rating"}},
+			"count": bson.M{"$sum": 1},
+			"sum":   bson.M{"$sum": "$rating"},
+		}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var groups []struct {
+		ID struct {
+			Book   primitive.ObjectID `bson:"book"`
+			Bucket float64            `bson:"bucket"`
+		} `bson:"_id"`
+		Count int64   `bson:"count"`
+		Sum   float64 `bson:"sum"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return 0, err
+	}
+
+	type aggregate struct {
+		count      int64
+		sum        float64
+		histogram  bson.M
+		dimensions bson.M
+	}
+	aggregates := map[primitive.ObjectID]*aggregate{}
+	aggregateFor := func(bookID primitive.ObjectID) *aggregate {
+		agg, ok := aggregates[bookID]
+		if !ok {
+			agg = &aggregate{histogram: emptyHistogram(), dimensions: bson.M{}}
+			aggregates[bookID] = agg
+		}
+		return agg
+	}
+
+	for _, group := range groups {
+		agg := aggregateFor(group.ID.Book)
+		agg.count += group.Count
+		agg.sum += group.Sum
+		agg.histogram[histogramBucket(group.ID.Bucket)] = group.Count
+	}
+
+	cursor, err = reviews.Aggregate(ctx, mongo.Pipeline{
+		counted,
+		{{Key: "$project", Value: bson.M{
+			"book":    "$book._id",
+			"ratings": bson.M{"$objectToArray": bson.M{"$ifNull": bson.A{"$ratings", bson.M{}}}},
+		}}},
+		{{Key: "$unwind", Value: "$ratings"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"book": "$book", "dimension": "$ratings.k"},
+			"count": bson.M{"$sum": 1},
+			"sum":   bson.M{"$sum": "$ratings.v"},
+		}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var dimensionGroups []struct {
+		ID struct {
+			Book      primitive.ObjectID `bson:"book"`
+			Dimension string             `bson:"dimension"`
+		} `bson:"_id"`
+		Count int64   `bson:"count"`
+		Sum   float64 `bson:"sum"`
+	}
+	if err := cursor.All(ctx, &dimensionGroups); err != nil {
+		return 0, err
+	}
+
+	for _, group := range dimensionGroups {
+		aggregateFor(group.ID.Book).dimensions[group.ID.Dimension] = models.DimensionRating{
+			Avg:   group.Sum / float64(group.Count),
+			Count: group.Count,
+			Sum:   group.Sum,
+		}
+	}
+
+	bookCursor, err := books.Find(ctx, bson.M{"deleted_at": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, err
+	}
+	defer bookCursor.Close(ctx)
+
+	updated := 0
+	for bookCursor.Next(ctx) {
+		var book struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := bookCursor.Decode(&book); err != nil {
+			return updated, err
+		}
+
+		agg, ok := aggregates[book.ID]
+		if !ok {
+			agg = &aggregate{histogram: emptyHistogram(), dimensions: bson.M{}}
+		}
+		avg := 0.0
+		if agg.count > 0 {
+			avg = agg.sum / float64(agg.count)
+		}
+
+		_, err := books.UpdateOne(ctx, bson.M{"_id": book.ID}, bson.M{"$set": bson.M{
+			"rating_count":      agg.count,
+			"rating_sum":        agg.sum,
+			"rating_avg":        avg,
+			"rating_histogram":  agg.histogram,
+			"dimension_ratings": agg.dimensions,
+		}})
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, bookCursor.Err()
+}
+
+func emptyHistogram() bson.M {
+	return bson.M{"1": int64(0), "2": int64(0), "3": int64(0), "4": int64(0), "5": int64(0)}
+}
@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"spa_media_review/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrSessionNotFound    = errors.New("session not found or expired")
+	ErrSessionRevoked     = errors.New("session revoked")
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// refreshReuseGrace is how long the refresh token a session has just
+// replaced keeps working. Several requests sent at once with an expired
+// access token all try to refresh with the same cookie, and only the first
+// of them gets to rotate it.
+const refreshReuseGrace = 30 * time.Second
+
+// sessionTouchInterval limits how often a session's last_seen_at is
+// written while it is in use.
+const sessionTouchInterval = time.Minute
+
+// CreateSession stores a new session lasting ttl. The caller fills in the
+// user, the ID of the first refresh token and the device details.
+func CreateSession(ctx context.Context, sessions *mongo.Collection, session models.Session, ttl time.Duration) (models.Session, error) {
+	now := time.Now()
+	session.ID = primitive.NewObjectID()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(ttl)
+	_, err := sessions.InsertOne(ctx, session)
+	return session, err
+}
+
+// CheckSession returns ErrSessionRevoked or ErrSessionNotFound unless the
+// session is live, and records that it has just been seen.
+func CheckSession(ctx context.Context, sessions *mongo.Collection, sessionID primitive.ObjectID) error {
+	now := time.Now()
+
+	var session models.Session
+	err := sessions.FindOne(
+		ctx,
+		bson.M{"_id": sessionID},
+		options.FindOne().SetProjection(bson.M{"revoked_at": 1, "expires_at": 1, "last_seen_at": 1}),
+	).Decode(&session)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		return ErrSessionNotFound
+	case err != nil:
+		return err
+	case session.RevokedAt != nil:
+		return ErrSessionRevoked
+	case !session.ExpiresAt.After(now):
+		return ErrSessionNotFound
+	}
+
+	if now.Sub(session.LastSeenAt) < sessionTouchInterval {
+		return nil
+	}
+	_, err = sessions.UpdateOne(ctx, bson.M{"_id": sessionID}, bson.M{"$set": bson.M{"last_seen_at": now}})
+	return err
+}
+
+// ListSessions returns a user's live sessions, most recently seen first.
+func ListSessions(ctx context.Context, sessions *mongo.Collection, userID primitive.ObjectID) ([]models.Session, error) {
+	cursor, err := sessions.Find(
+		ctx,
+		bson.M{
+			"user_id":    userID,
+			"revoked_at": bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+		options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []models.Session{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// RotateSession swaps the session's refresh token jti for next and extends
+// the session by ttl. It reports false, without rotating, when jti was
+// replaced less than refreshReuseGrace ago; the caller should then keep the
+// newer refresh token it already handed out. Any other stale jti revokes
+// the session and returns ErrRefreshTokenReused.
+func RotateSession(ctx context.Context, sessions *mongo.Collection, sessionID primitive.ObjectID, jti, next string, ttl time.Duration) (models.Session, bool, error) {
+	now := time.Now()
+
+	var session models.Session
+	err := sessions.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"_id":         sessionID,
+			"refresh_jti": jti,
+			"revoked_at":  bson.M{"$exists": false},
+			"expires_at":  bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{
+			"refresh_jti":  next,
+			"previous_jti": jti,
+			"rotated_at":   now,
+			"last_seen_at": now,
+			"expires_at":   now.Add(ttl),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&session)
+	if err == nil {
+		return session, true, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return models.Session{}, false, err
+	}
+
+	if err := sessions.FindOne(ctx, bson.M{"_id": sessionID}).Decode(&session); err == mongo.ErrNoDocuments {
+		return models.Session{}, false, ErrSessionNotFound
+	} else if err != nil {
+		return models.Session{}, false, err
+	}
+
+	switch {
+	case session.RevokedAt != nil:
+		return models.Session{}, false, ErrSessionRevoked
+	case !session.ExpiresAt.After(now):
+		return models.Session{}, false, ErrSessionNotFound
+	case session.PreviousJTI == jti && session.RotatedAt != nil && now.Sub(*session.RotatedAt) < refreshReuseGrace:
+		_, err := sessions.UpdateOne(ctx, bson.M{"_id": sessionID}, bson.M{"$set": bson.M{"last_seen_at": now}})
+		return session, false, err
+	}
+
+	if _, err := RevokeSessions(ctx, sessions, bson.M{"_id": sessionID}, models.SessionRevokedTokenReuse); err != nil {
+		return models.Session{}, false, err
+	}
+	return models.Session{}, false, ErrRefreshTokenReused
+}
+
+// RevokeSessions revokes the live sessions matching filter, recording why.
+func RevokeSessions(ctx context.Context, sessions *mongo.Collection, filter bson.M, reason string) (int64, error) {
+	filter["revoked_at"] = bson.M{"$exists": false}
+	result, err := sessions.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": reason}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// RevokeUserTokens signs a user out everywhere: every token issued to them
+// before now stops working and all of their sessions are revoked.
+func RevokeUserTokens(ctx context.Context, users, sessions *mongo.Collection, userID primitive.ObjectID, reason string) error {
+	// Tokens carry their issue time in whole seconds.
+	now := time.Now().Truncate(time.Second)
+	if _, err := users.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"tokens_valid_after": now}}); err != nil {
+		return err
+	}
+	_, err := RevokeSessions(ctx, sessions, bson.M{"user_id": userID}, reason)
+	return err
+}
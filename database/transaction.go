@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var transactionSupport sync.Map
+
+// WithTransaction runs fn as a single unit of work inside a session
+// transaction. The driver retries the whole callback on transient
+// transaction errors and retries the commit when its outcome is unknown,
+// so fn must be safe to run more than once.
+//
+// Standalone servers cannot run transactions; there fn runs once inside a
+// plain session and its writes are applied individually.
+func WithTransaction(ctx context.Context, client *mongo.Client, fn func(sessCtx mongo.SessionContext) error) error {
+	if !supportsTransactions(ctx, client) {
+		return client.UseSession(ctx, fn)
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// supportsTransactions reports whether the deployment is a replica set or a
+// sharded cluster. The answer is cached per client.
+func supportsTransactions(ctx context.Context, client *mongo.Client) bool {
+	if supported, ok := transactionSupport.Load(client); ok {
+		return supported.(bool)
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		log.Printf("Could not determine transaction support, running without transactions: %v", err)
+		return false
+	}
+
+	supported := hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !supported {
+		log.Println("MongoDB is running standalone; multi-document writes will not be transactional")
+	}
+	transactionSupport.Store(client, supported)
+	return supported
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"log"
+	"spa_media_review/imaging"
+	"spa_media_review/models"
+	"spa_media_review/storage"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const trashPurgeInterval = time.Hour
+
+// TrashCollections are the collections PurgeTrash removes documents from.
+type TrashCollections struct {
+	Books    *mongo.Collection
+	Reviews  *mongo.Collection
+	Votes    *mongo.Collection
+	Comments *mongo.Collection
+	Reports  *mongo.Collection
+}
+
+// Trash returns the application's collections for PurgeTrash. It must be
+// called after Connect_to_mongodb.
+func Trash() TrashCollections {
+	return TrashCollections{
+		Books:    BookCollection,
+		Reviews:  ReviewCollection,
+		Votes:    VoteCollection,
+		Comments: CommentCollection,
+		Reports:  ReportCollection,
+	}
+}
+
+// PurgeTrash permanently removes books, reviews and comments that were moved
+// to the trash before cutoff, together with the covers of the purged books
+// and the votes, comments and reports on the purged reviews.
+func PurgeTrash(ctx context.Context, c TrashCollections, store storage.BlobStore, cutoff time.Time) (int64, int64, error) {
+	cursor, err := c.Books.Find(ctx, bson.M{"deleted_at": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var purgedBooks, purgedReviews int64
+	for cursor.Next(ctx) {
+		var book models.Book
+		if err := cursor.Decode(&book); err != nil {
+			return purgedBooks, purgedReviews, err
+		}
+
+		purged, err := purgeReviews(ctx, c, bson.M{"book._id": book.ID})
+		purgedReviews += purged
+		if err != nil {
+			return purgedBooks, purgedReviews, err
+		}
+
+		if _, err := c.Books.DeleteOne(ctx, bson.M{"_id": book.ID}); err != nil {
+			return purgedBooks, purgedReviews, err
+		}
+		purgedBooks++
+
+		if err := imaging.DeleteCover(ctx, store, book); err != nil {
+			log.Printf("Failed to delete cover for purged book %s: %v", book.ID.Hex(), err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return purgedBooks, purgedReviews, err
+	}
+
+	purged, err := purgeReviews(ctx, c, bson.M{"deleted_at": bson.M{"$lt": cutoff}})
+	purgedReviews += purged
+	if err != nil {
+		return purgedBooks, purgedReviews, err
+	}
+
+	_, err = c.Comments.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": cutoff}})
+	return purgedBooks, purgedReviews, err
+}
+
+// purgeReviews deletes the reviews matching filter along with their votes,
+// comments and reports.
+func purgeReviews(ctx context.Context, c TrashCollections, filter bson.M) (int64, error) {
+	cursor, err := c.Reviews.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+
+	var found []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &found); err != nil {
+		return 0, err
+	}
+	if len(found) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(found))
+	for i, review := range found {
+		ids[i] = review.ID
+	}
+
+	byReview := bson.M{"review_id": bson.M{"$in": ids}}
+	for _, related := range []*mongo.Collection{c.Votes, c.Comments, c.Reports} {
+		if _, err := related.DeleteMany(ctx, byReview); err != nil {
+			return 0, err
+		}
+	}
+	result, err := c.Reviews.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// StartTrashPurger purges expired trash in the background once an hour.
+func StartTrashPurger(c TrashCollections, store storage.BlobStore, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for ; ; <-ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			purgedBooks, purgedReviews, err := PurgeTrash(ctx, c, store, time.Now().Add(-retention))
+			cancel()
+			if err != nil {
+				log.Printf("Trash purge failed: %v", err)
+			} else if purgedBooks > 0 || purgedReviews > 0 {
+				log.Printf("Purged %d books and %d reviews from the trash", purgedBooks, purgedReviews)
+			}
+		}
+	}()
+}
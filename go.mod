@@ -9,6 +9,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	go.mongodb.org/mongo-driver v1.17.1
 	golang.org/x/crypto v0.28.0
+	golang.org/x/image v0.21.0
 )
 
 require (
@@ -0,0 +1,55 @@
+package imaging
+
+import (
+	"context"
+	"spa_media_review/models"
+	"spa_media_review/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SaveCover processes an uploaded cover and writes every variant to the blob
+// store under new keys, returning the variant descriptions to record on the
+// book. The book's previous cover is left alone; delete it with DeleteCover
+// once the book no longer refers to it.
+func SaveCover(ctx context.Context, store storage.BlobStore, bookID primitive.ObjectID, data []byte) (map[string]models.ImageVariant, error) {
+	variants, err := Process(data)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := primitive.NewObjectID().Hex()
+	images := make(map[string]models.ImageVariant, len(variants))
+	for _, variant := range variants {
+		key := models.CoverKey(bookID, revision, variant.Name)
+		if _, err := store.Put(ctx, key, variant.ContentType, variant.Data); err != nil {
+			return nil, err
+		}
+		images[variant.Name] = models.ImageVariant{
+			Key:         key,
+			URL:         models.CoverURL(bookID, variant.Name),
+			ContentType: variant.ContentType,
+			Width:       variant.Width,
+			Height:      variant.Height,
+		}
+	}
+	return images, nil
+}
+
+// DeleteCover removes every stored variant of a book's cover.
+func DeleteCover(ctx context.Context, store storage.BlobStore, book models.Book) error {
+	keys := map[string]bool{}
+	if book.ImageKey != "" {
+		keys[book.ImageKey] = true
+	}
+	for _, variant := range book.Images {
+		keys[variant.Key] = true
+	}
+
+	for key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
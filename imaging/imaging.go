@@ -0,0 +1,129 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	MaxUploadSize = 10 << 20
+	MaxDimension  = 8000
+	MaxPixels     = 40_000_000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("image must be a JPEG, PNG, WebP or GIF")
+	ErrTooLarge          = errors.New("image dimensions exceed the allowed maximum")
+)
+
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/gif":  true,
+}
+
+// Sizes lists the variants generated for every upload, each scaled to fit
+// inside a square of the given edge length.
+var Sizes = []struct {
+	Name   string
+	MaxDim int
+}{
+	{"thumbnail", 200},
+	{"medium", 600},
+	{"full", 1600},
+}
+
+type Variant struct {
+	Name        string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// Process validates an uploaded image and re-encodes it into every size in
+// Sizes. Re-encoding drops EXIF and any other embedded metadata, so the
+// EXIF orientation is applied to the pixels first.
+func Process(data []byte) ([]Variant, error) {
+	if !allowedTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedFormat
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width > MaxDimension || config.Height > MaxDimension || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	orientation := jpegOrientation(data)
+
+	variants := make([]Variant, 0, len(Sizes))
+	for _, size := range Sizes {
+		img := orient(scale(src, size.MaxDim), orientation)
+
+		var buf bytes.Buffer
+		contentType := "image/jpeg"
+		if isOpaque(img) {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		} else {
+			contentType = "image/png"
+			err = png.Encode(&buf, img)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		bounds := img.Bounds()
+		variants = append(variants, Variant{
+			Name:        size.Name,
+			ContentType: contentType,
+			Width:       bounds.Dx(),
+			Height:      bounds.Dy(),
+			Data:        buf.Bytes(),
+		})
+	}
+	return variants, nil
+}
+
+func scale(src image.Image, maxDim int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxDim || height > maxDim {
+		if width >= height {
+			width, height = maxDim, max(1, height*maxDim/width)
+		} else {
+			width, height = max(1, width*maxDim/height), maxDim
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if width == bounds.Dx() && height == bounds.Dy() {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	} else {
+		xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, xdraw.Src, nil)
+	}
+	return dst
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
 	"spa_media_review/config"
 	"spa_media_review/database"
+	"spa_media_review/middleware"
 )
 
 func init() {
@@ -15,6 +17,19 @@ func init() {
 		log.Fatal("Could not connect to MongoDB:", err)
 	}
 
+	// Duplicate votes and reports are only kept out by unique indexes, so
+	// the server must not run without them.
+	if err := database.EnsureIndexes(database.DB); err != nil {
+		var indexErr *database.IndexError
+		if errors.As(err, &indexErr) && indexErr.Missing(database.ReviewUserBookIndex) {
+			log.Printf("The reviews collection holds duplicate reviews; run `go run ./cmd/admin dedupe-reviews` and restart")
+		}
+		if errors.As(err, &indexErr) && indexErr.Missing(database.UserEmailIndex) {
+			log.Printf("Some accounts share an email address; run `go run ./cmd/admin normalize-emails` to list them, merge or remove them, and restart")
+		}
+		log.Fatal("Index setup: ", err)
+	}
+
 	if err := database.SetupAdminUser(database.DB); err != nil {
 		log.Printf("Admin user setup: %v", err)
 	}
@@ -27,7 +42,38 @@ func main() {
 
 	router := config.SetupServer()
 
-	config.SetupHandlers(router, database.BookCollection, database.ReviewCollection, database.UserCollection)
+	blobStore, err := config.SetupBlobStore(database.DB)
+	if err != nil {
+		log.Fatal("Could not set up blob storage:", err)
+	}
+
+	reviewFilter, err := config.SetupContentFilter(database.ReviewCollection)
+	if err != nil {
+		log.Fatal("Could not set up the review content filter:", err)
+	}
+
+	revocations, err := config.SetupRevocationStore(database.DB)
+	if err != nil {
+		log.Fatal("Could not set up the token revocation store:", err)
+	}
+	middleware.UseRevocationStore(revocations)
+
+	mail, err := config.SetupMailer()
+	if err != nil {
+		log.Fatal("Could not set up the mailer:", err)
+	}
+
+	if err := config.CheckEmailVerification(); err != nil {
+		log.Fatal("Invalid email verification settings: ", err)
+	}
+
+	if err := config.SetupRatingDimensions(); err != nil {
+		log.Fatal("Could not set up rating dimensions:", err)
+	}
+
+	database.StartTrashPurger(database.Trash(), blobStore, config.TrashRetention())
+
+	config.SetupHandlers(router, database.BookCollection, database.ReviewCollection, database.UserCollection, database.VoteCollection, database.CommentCollection, database.ReportCollection, database.SessionCollection, blobStore, reviewFilter, mail)
 
 	fmt.Printf("Starting the server on port %s\n", config.GetEnv("PORT", "8000"))
 	if err := router.Run(":" + config.GetEnv("PORT", "8000")); err != nil {
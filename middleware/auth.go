@@ -5,17 +5,19 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"spa_media_review/models"
+	"spa_media_review/database"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
 type Claims struct {
-	UserID   string `json:"sub"`
-	Username string `json:"username"`
-	IsAdmin  bool   `json:"isAdmin"`
+	UserID    string `json:"sub"`
+	Username  string `json:"username"`
+	IsAdmin   bool   `json:"isAdmin"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -33,6 +35,12 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		if accessToken == "" {
+			// The access cookie expires together with its token, so a
+			// browser whose token has run out sends only the refresh cookie.
+			if _, err := ctx.Cookie("refresh_token"); err == nil {
+				refreshFromCookie(ctx)
+				return
+			}
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Access token not provided"})
 			ctx.Abort()
 			return
@@ -47,43 +55,7 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		if err != nil {
 			if err.Error() == "Token is expired" {
-				refreshToken, err := ctx.Cookie("refresh_token")
-				if err != nil {
-					ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token not provided"})
-					ctx.Abort()
-					return
-				}
-
-				_, err = jwt.ParseWithClaims(refreshToken, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-					if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-						return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-					}
-					return []byte(os.Getenv("REFRESH_SECRET_KEY")), nil
-				})
-				if err != nil {
-					ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
-					ctx.Abort()
-					return
-				}
-
-				var user models.User
-				accessToken, err := GenerateToken(user)
-				if err != nil {
-					ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
-					ctx.Abort()
-					return
-				}
-
-				domain, secure, httpOnly, err := GetCookieSettings()
-				if err != nil {
-					log.Fatalf("Failed to parse environment variables: %v", err)
-				}
-
-				ctx.SetCookie("access_token", accessToken, 3600*24, "/", domain, secure, httpOnly)
-
-				ctx.Set("userID", user.ID.Hex())
-				ctx.Set("isAdmin", user.IsAdmin)
-				ctx.Next()
+				refreshFromCookie(ctx)
 				return
 			}
 
@@ -93,8 +65,14 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+			if !checkNotRevoked(ctx, claims) || !checkSession(ctx, claims) {
+				return
+			}
 			ctx.Set("userID", claims.UserID)
 			ctx.Set("isAdmin", claims.IsAdmin)
+			ctx.Set("sessionID", claims.SessionID)
+			ctx.Set("tokenID", claims.Id)
+			ctx.Set("tokenExpiresAt", time.Unix(claims.ExpiresAt, 0))
 			ctx.Next()
 		} else {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
@@ -102,3 +80,46 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 	}
 }
+
+// refreshFromCookie signs the request in with the refresh token cookie,
+// rotating it and setting a new access token cookie, and then runs the rest
+// of the chain. It writes the error response itself on failure.
+func refreshFromCookie(ctx *gin.Context) {
+	refreshToken, err := ctx.Cookie("refresh_token")
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token not provided"})
+		ctx.Abort()
+		return
+	}
+
+	user, tokens, err := RefreshSession(ctx, refreshToken)
+	switch {
+	case err == ErrInvalidRefreshToken:
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		ctx.Abort()
+		return
+	case err == database.ErrRefreshTokenReused:
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used, please log in again"})
+		ctx.Abort()
+		return
+	case err != nil:
+		log.Printf("Failed to refresh session: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		ctx.Abort()
+		return
+	}
+
+	if err := SetAuthCookies(ctx, tokens); err != nil {
+		log.Printf("Failed to set auth cookies: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not set auth cookies"})
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set("userID", user.ID.Hex())
+	ctx.Set("isAdmin", user.IsAdmin)
+	ctx.Set("sessionID", tokens.SessionID)
+	ctx.Set("tokenID", tokens.AccessTokenID)
+	ctx.Set("tokenExpiresAt", time.Now().Add(AccessTokenTTL))
+	ctx.Next()
+}
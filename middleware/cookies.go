@@ -1,9 +1,13 @@
 package middleware
 
 import (
+	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/gin-gonic/gin"
 )
 
 func GetCookieSettings() (string, bool, bool, error) {
@@ -51,3 +55,20 @@ func extractDomain(fullOrigin string) string {
 	}
 	return fullOrigin
 }
+
+// SetAuthCookies stores a session's tokens in cookies that last as long as
+// the tokens do. An empty refresh token leaves that cookie alone.
+func SetAuthCookies(ctx *gin.Context, tokens SessionTokens) error {
+	domain, secure, httpOnly, err := GetCookieSettings()
+	if err != nil {
+		return fmt.Errorf("failed to parse cookie settings: %v", err)
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie("access_token", tokens.AccessToken, int(AccessTokenTTL.Seconds()), "/", domain, secure, httpOnly)
+	if tokens.RefreshToken != "" {
+		ctx.SetSameSite(http.SameSiteLaxMode)
+		ctx.SetCookie("refresh_token", tokens.RefreshToken, int(RefreshTokenTTL.Seconds()), "/", domain, secure, httpOnly)
+	}
+	return nil
+}
@@ -28,6 +28,7 @@ func CORSMiddleware() gin.HandlerFunc {
 			"GET",
 			"POST",
 			"PUT",
+			"PATCH",
 			"DELETE",
 			"OPTIONS",
 		},
@@ -40,11 +41,14 @@ func CORSMiddleware() gin.HandlerFunc {
 			"Origin",
 			"Cache-Control",
 			"X-Requested-With",
+			"If-Match",
+			"If-None-Match",
 		},
 		ExposeHeaders: []string{
 			"Content-Length",
 			"Content-Type",
 			"Content-Disposition",
+			"ETag",
 		},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Actor roles stored under "actorRole" by RequireOwnerOrAdmin.
+const (
+	ActorOwner     = "owner"
+	ActorModerator = "moderator"
+)
+
+// ErrResourceNotFound is returned by an owner lookup when the resource does
+// not exist or its ID is malformed.
+var ErrResourceNotFound = errors.New("resource not found")
+
+// RequireOwnerOrAdmin lets a request through when the authenticated user
+// owns the resource, as reported by ownerOf, or is an admin. It must run
+// after AuthMiddleware. The user's role is stored as "actorRole" so that
+// handlers can tell an owner's change from a moderator's.
+func RequireOwnerOrAdmin(ownerOf func(*gin.Context) (string, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID := ctx.GetString("userID")
+		if userID == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			ctx.Abort()
+			return
+		}
+		isAdmin := ctx.GetBool("isAdmin")
+
+		ownerID, err := ownerOf(ctx)
+		switch {
+		case errors.Is(err, ErrResourceNotFound) && isAdmin:
+			// Let the handler report the missing resource in its own words.
+			ctx.Set("actorRole", ActorModerator)
+			ctx.Next()
+			return
+		case errors.Is(err, ErrResourceNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			ctx.Abort()
+			return
+		case err != nil:
+			log.Printf("Owner lookup failed for %s: %v", ctx.Request.URL.Path, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			ctx.Abort()
+			return
+		}
+
+		switch {
+		case ownerID == userID:
+			ctx.Set("actorRole", ActorOwner)
+		case isAdmin:
+			ctx.Set("actorRole", ActorModerator)
+		default:
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "You can only change your own content"})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
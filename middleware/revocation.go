@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"spa_media_review/database"
+	"spa_media_review/revocation"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var revokedTokens revocation.Store = revocation.NewMemoryStore()
+
+// UseRevocationStore sets where revoked token IDs are kept. It must be
+// called before the server starts; the default is an in-memory store.
+func UseRevocationStore(store revocation.Store) {
+	revokedTokens = store
+}
+
+// RevokeToken stops the token with the given ID from being accepted again.
+func RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	return revokedTokens.Revoke(ctx, tokenID, expiresAt)
+}
+
+// checkNotRevoked rejects tokens that were revoked one by one, tokens issued
+// before the user's tokens_valid_after, which is moved forward when they
+// sign out everywhere or change their password, and tokens of users that
+// are banned or no longer exist. It writes the error response itself and
+// returns false when the request must not go on.
+func checkNotRevoked(ctx *gin.Context, claims *Claims) bool {
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		ctx.Abort()
+		return false
+	}
+
+	if claims.Id != "" {
+		revoked, err := revokedTokens.IsRevoked(ctx, claims.Id)
+		if err != nil {
+			log.Printf("Failed to check token revocation for user %s: %v", claims.UserID, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify token"})
+			ctx.Abort()
+			return false
+		}
+		if revoked {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			ctx.Abort()
+			return false
+		}
+	}
+
+	var user struct {
+		TokensValidAfter *time.Time `bson:"tokens_valid_after"`
+		BannedAt         *time.Time `bson:"banned_at"`
+	}
+	err = database.UserCollection.FindOne(
+		ctx,
+		bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"tokens_valid_after": 1, "banned_at": 1}),
+	).Decode(&user)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		ctx.Abort()
+		return false
+	case err != nil:
+		log.Printf("Failed to check token revocation for user %s: %v", claims.UserID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify token"})
+		ctx.Abort()
+		return false
+	}
+
+	if user.BannedAt != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "This account has been banned"})
+		ctx.Abort()
+		return false
+	}
+	if user.TokensValidAfter != nil && claims.IssuedAt < user.TokensValidAfter.Unix() {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		ctx.Abort()
+		return false
+	}
+	return true
+}
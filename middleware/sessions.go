@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"spa_media_review/database"
+	"spa_media_review/models"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// SessionTokens are the tokens handed to a client for one session.
+type SessionTokens struct {
+	SessionID     string
+	AccessToken   string
+	AccessTokenID string
+	RefreshToken  string
+}
+
+// StartSession signs a user in from the device making the request, returning
+// the tokens of a new session.
+func StartSession(ctx *gin.Context, user models.User) (SessionTokens, error) {
+	jti, err := newJTI()
+	if err != nil {
+		return SessionTokens{}, err
+	}
+	session, err := database.CreateSession(ctx, database.SessionCollection, models.Session{
+		UserID:     user.ID,
+		RefreshJTI: jti,
+		UserAgent:  ctx.Request.UserAgent(),
+		IP:         ctx.ClientIP(),
+	}, RefreshTokenTTL)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to create session: %v", err)
+	}
+
+	tokens := SessionTokens{SessionID: session.ID.Hex()}
+	if tokens.AccessTokenID, err = newJTI(); err != nil {
+		return SessionTokens{}, err
+	}
+	if tokens.AccessToken, err = GenerateToken(user, tokens.SessionID, tokens.AccessTokenID); err != nil {
+		return SessionTokens{}, err
+	}
+	if tokens.RefreshToken, err = GenerateRefreshToken(user, tokens.SessionID, jti); err != nil {
+		return SessionTokens{}, err
+	}
+	return tokens, nil
+}
+
+// RefreshSession trades a refresh token for a new access token and the
+// session's next refresh token. The new refresh token is "" when the one
+// given was replaced moments ago by a concurrent request, in which case the
+// client already has the newer one. Reusing an older refresh token revokes
+// the session and returns database.ErrRefreshTokenReused.
+func RefreshSession(ctx context.Context, refreshToken string) (models.User, SessionTokens, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(refreshToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(os.Getenv("REFRESH_SECRET_KEY")), nil
+	})
+	if err != nil || claims.Id == "" {
+		return models.User{}, SessionTokens{}, ErrInvalidRefreshToken
+	}
+	sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		return models.User{}, SessionTokens{}, ErrInvalidRefreshToken
+	}
+
+	next, err := newJTI()
+	if err != nil {
+		return models.User{}, SessionTokens{}, err
+	}
+	session, rotated, err := database.RotateSession(ctx, database.SessionCollection, sessionID, claims.Id, next, RefreshTokenTTL)
+	switch {
+	case err == database.ErrSessionNotFound, err == database.ErrSessionRevoked:
+		return models.User{}, SessionTokens{}, ErrInvalidRefreshToken
+	case err != nil:
+		return models.User{}, SessionTokens{}, err
+	case session.UserID.Hex() != claims.UserID:
+		return models.User{}, SessionTokens{}, ErrInvalidRefreshToken
+	}
+
+	// The user is loaded afresh so that the new access token carries their
+	// current name and admin status.
+	var user models.User
+	if err := database.UserCollection.FindOne(ctx, bson.M{"_id": session.UserID}).Decode(&user); err != nil {
+		return models.User{}, SessionTokens{}, ErrInvalidRefreshToken
+	}
+	if user.BannedAt != nil {
+		return models.User{}, SessionTokens{}, ErrInvalidRefreshToken
+	}
+
+	tokens := SessionTokens{SessionID: session.ID.Hex()}
+	if tokens.AccessTokenID, err = newJTI(); err != nil {
+		return models.User{}, SessionTokens{}, err
+	}
+	if tokens.AccessToken, err = GenerateToken(user, tokens.SessionID, tokens.AccessTokenID); err != nil {
+		return models.User{}, SessionTokens{}, err
+	}
+	if rotated {
+		if tokens.RefreshToken, err = GenerateRefreshToken(user, tokens.SessionID, next); err != nil {
+			return models.User{}, SessionTokens{}, err
+		}
+	}
+	return user, tokens, nil
+}
+
+func newJTI() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// checkSession rejects access tokens whose session has been revoked or has
+// ended. Tokens issued before sessions existed carry no session and are let
+// through until they expire. It writes the error response itself and
+// returns false when the request must not go on.
+func checkSession(ctx *gin.Context, claims *Claims) bool {
+	if claims.SessionID == "" {
+		return true
+	}
+	sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		ctx.Abort()
+		return false
+	}
+
+	switch err := database.CheckSession(ctx, database.SessionCollection, sessionID); {
+	case err == database.ErrSessionRevoked, err == database.ErrSessionNotFound:
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+		ctx.Abort()
+		return false
+	case err != nil:
+		log.Printf("Failed to check session %s: %v", claims.SessionID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify token"})
+		ctx.Abort()
+		return false
+	}
+	return true
+}
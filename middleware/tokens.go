@@ -9,13 +9,25 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
-func GenerateToken(user models.User) (string, error) {
+// AccessTokenTTL and RefreshTokenTTL are how long the two tokens, and the
+// cookies holding them, last.
+const (
+	AccessTokenTTL  = 24 * time.Hour
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// GenerateToken signs an access token for a session. jti identifies the
+// token so that it can be revoked.
+func GenerateToken(user models.User, sessionID, jti string) (string, error) {
 	claims := Claims{
-		UserID:   user.ID.Hex(),
-		Username: user.Username,
-		IsAdmin:  user.IsAdmin,
+		UserID:    user.ID.Hex(),
+		Username:  user.Username,
+		IsAdmin:   user.IsAdmin,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
+			Id:        jti,
+			ExpiresAt: time.Now().Add(AccessTokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
 		},
 	}
 
@@ -28,13 +40,18 @@ func GenerateToken(user models.User) (string, error) {
 	return token.SignedString([]byte(accessSecret))
 }
 
-func GenerateRefreshToken(user models.User) (string, error) {
+// GenerateRefreshToken signs a refresh token for a session. jti must be the
+// session's current refresh token ID.
+func GenerateRefreshToken(user models.User, sessionID, jti string) (string, error) {
 	claims := Claims{
-		UserID:   user.ID.Hex(),
-		Username: user.Username,
-		IsAdmin:  user.IsAdmin,
+		UserID:    user.ID.Hex(),
+		Username:  user.Username,
+		IsAdmin:   user.IsAdmin,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 24 * 30).Unix(),
+			Id:        jti,
+			ExpiresAt: time.Now().Add(RefreshTokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
 		},
 	}
 
@@ -46,3 +63,53 @@ func GenerateRefreshToken(user models.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(refreshSecret))
 }
+
+// EmailVerificationTTL is how long the link in a verification email works.
+const EmailVerificationTTL = 24 * time.Hour
+
+// EmailVerificationClaims are carried by the link that confirms a user's
+// email address. They are signed with their own key so that they can never
+// pass for an access token.
+type EmailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.StandardClaims
+}
+
+func GenerateEmailVerificationToken(user models.User) (string, error) {
+	claims := EmailVerificationClaims{
+		Email: user.Email,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   user.ID.Hex(),
+			ExpiresAt: time.Now().Add(EmailVerificationTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	}
+
+	verifySecret := os.Getenv("VERIFY_SECRET_KEY")
+	if verifySecret == "" {
+		return "", fmt.Errorf("verification secret key not set in environment")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(verifySecret))
+}
+
+func ParseEmailVerificationToken(tokenString string) (*EmailVerificationClaims, error) {
+	// An empty key would accept tokens anyone can sign.
+	verifySecret := os.Getenv("VERIFY_SECRET_KEY")
+	if verifySecret == "" {
+		return nil, fmt.Errorf("verification secret key not set in environment")
+	}
+
+	claims := &EmailVerificationClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(verifySecret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
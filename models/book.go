@@ -7,14 +7,39 @@ import (
 )
 
 type Book struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Title       string             `json:"title" bson:"title"`
-	Author      string             `json:"author" bson:"author"`
-	Category    string             `json:"category" bson:"category"`
-	Description string             `json:"description" bson:"description"`
-	Image       string             `json:"image" bson:"image,omitempty"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+	ID               primitive.ObjectID         `json:"id" bson:"_id,omitempty"`
+	Title            string                     `json:"title" bson:"title"`
+	Author           string                     `json:"author" bson:"author"`
+	Category         string                     `json:"category" bson:"category"`
+	Description      string                     `json:"description" bson:"description"`
+	ISBN10           string                     `json:"isbn10,omitempty" bson:"isbn10,omitempty"`
+	ISBN13           string                     `json:"isbn13,omitempty" bson:"isbn13,omitempty"`
+	ImageKey         string                     `json:"image_key,omitempty" bson:"image_key,omitempty"`
+	Images           map[string]ImageVariant    `json:"images,omitempty" bson:"images,omitempty"`
+	CreatedAt        time.Time                  `json:"created_at" bson:"created_at"`
+	UpdatedAt        time.Time                  `json:"updated_at" bson:"updated_at"`
+	RatingAvg        float64                    `json:"rating_avg" bson:"rating_avg"`
+	RatingCount      int64                      `json:"rating_count" bson:"rating_count"`
+	RatingSum        float64                    `json:"-" bson:"rating_sum"`
+	RatingHistogram  map[string]int64           `json:"rating_histogram,omitempty" bson:"rating_histogram,omitempty"`
+	DimensionRatings map[string]DimensionRating `json:"dimension_ratings,omitempty" bson:"dimension_ratings,omitempty"`
+	Version          int64                      `json:"version" bson:"version"`
+	DeletedAt        *time.Time                 `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy        *primitive.ObjectID        `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	Score            float64                    `json:"score,omitempty" bson:"score,omitempty"`
+}
+
+// BookSummary is the snapshot of a book stored on the documents that
+// reference it.
+type BookSummary struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
+	Title    string             `json:"title" bson:"title"`
+	Author   string             `json:"author" bson:"author"`
+	Category string             `json:"category" bson:"category"`
+}
+
+func (b Book) Summary() BookSummary {
+	return BookSummary{ID: b.ID, Title: b.Title, Author: b.Author, Category: b.Category}
 }
 
 func (b *Book) Validate() map[string]string {
@@ -34,9 +59,63 @@ func (b *Book) Validate() map[string]string {
 		errors["description"] = "Description is required"
 	}
 
-	if b.Image == "" {
+	if b.ImageKey == "" {
 		errors["image"] = "Image is required"
 	}
 
+	b.validateISBN(errors)
+
 	return errors
 }
+
+// validateISBN normalizes both ISBN fields, checks their check digits and
+// fills in whichever form is missing.
+func (b *Book) validateISBN(errors map[string]string) {
+	var fromISBN10 string
+	if b.ISBN10 != "" {
+		b.ISBN10 = cleanISBN(b.ISBN10)
+		if !validISBN10(b.ISBN10) {
+			errors["isbn10"] = "Invalid ISBN-10"
+			return
+		}
+		fromISBN10 = isbn10To13(b.ISBN10)
+	}
+
+	if b.ISBN13 != "" {
+		b.ISBN13 = cleanISBN(b.ISBN13)
+		if !validISBN13(b.ISBN13) {
+			errors["isbn13"] = "Invalid ISBN-13"
+			return
+		}
+		if fromISBN10 != "" && fromISBN10 != b.ISBN13 {
+			errors["isbn13"] = "ISBN-10 and ISBN-13 do not refer to the same book"
+			return
+		}
+	}
+
+	if b.ISBN13 == "" {
+		b.ISBN13 = fromISBN10
+	}
+	if b.ISBN10 == "" {
+		b.ISBN10 = isbn13To10(b.ISBN13)
+	}
+}
+
+type ImageVariant struct {
+	Key         string `json:"-" bson:"key"`
+	URL         string `json:"url" bson:"url"`
+	ContentType string `json:"content_type" bson:"content_type"`
+	Width       int    `json:"width" bson:"width"`
+	Height      int    `json:"height" bson:"height"`
+}
+
+// CoverKey is where one size of an uploaded cover is stored. Every upload
+// gets its own revision, so a new cover never overwrites the blobs the book
+// document still points to.
+func CoverKey(bookID primitive.ObjectID, revision, size string) string {
+	return "covers/" + bookID.Hex() + "/" + revision + "/" + size
+}
+
+func CoverURL(bookID primitive.ObjectID, size string) string {
+	return "/api/books/" + bookID.Hex() + "/cover?size=" + size
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const MaxCommentLength = 2000
+
+// Comment is a reply to a review or to another comment on the same review.
+// Ancestors lists the comment's parents from the top-level comment down, so
+// that a whole thread or subtree can be found with a single query.
+type Comment struct {
+	ID        primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	ReviewID  primitive.ObjectID   `json:"review_id" bson:"review_id"`
+	ParentID  *primitive.ObjectID  `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Ancestors []primitive.ObjectID `json:"-" bson:"ancestors"`
+	UserID    primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	Username  string               `json:"username" bson:"username"`
+	Body      string               `json:"body" bson:"body"`
+	CreatedAt time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt *time.Time           `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	EditedBy  string               `json:"edited_by,omitempty" bson:"edited_by,omitempty"`
+	Version   int64                `json:"version" bson:"version"`
+	DeletedAt *time.Time           `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy *primitive.ObjectID  `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	Replies   []Comment            `json:"replies,omitempty" bson:"-"`
+}
+
+// RootID returns the ID of the top-level comment of the comment's thread.
+func (c Comment) RootID() primitive.ObjectID {
+	if len(c.Ancestors) > 0 {
+		return c.Ancestors[0]
+	}
+	return c.ID
+}
+
+func (c *Comment) Validate() map[string]string {
+	errors := make(map[string]string)
+	c.Body = strings.TrimSpace(c.Body)
+	if c.Body == "" {
+		errors["body"] = "Comment is required"
+	} else if utf8.RuneCountInString(c.Body) > MaxCommentLength {
+		errors["body"] = "Comment must be at most 2000 characters"
+	}
+	return errors
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidISBN = errors.New("invalid ISBN")
+
+// NormalizeISBN accepts an ISBN-10 or ISBN-13, with or without hyphens and
+// spaces, verifies its check digit and returns the equivalent ISBN-13.
+func NormalizeISBN(raw string) (string, error) {
+	isbn := cleanISBN(raw)
+	switch {
+	case len(isbn) == 10 && validISBN10(isbn):
+		return isbn10To13(isbn), nil
+	case len(isbn) == 13 && validISBN13(isbn):
+		return isbn, nil
+	default:
+		return "", ErrInvalidISBN
+	}
+}
+
+func cleanISBN(raw string) string {
+	raw = strings.ToUpper(strings.TrimSpace(raw))
+	return strings.NewReplacer("-", "", " ", "").Replace(raw)
+}
+
+func validISBN10(isbn string) bool {
+	if len(isbn) != 10 {
+		return false
+	}
+	sum := 0
+	for i, c := range isbn {
+		var digit int
+		switch {
+		case c >= '0' && c <= '9':
+			digit = int(c - '0')
+		case c == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * digit
+	}
+	return sum%11 == 0
+}
+
+func validISBN13(isbn string) bool {
+	if len(isbn) != 13 {
+		return false
+	}
+	sum := 0
+	for i, c := range isbn {
+		if c < '0' || c > '9' {
+			return false
+		}
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(c-'0')
+	}
+	return sum%10 == 0
+}
+
+func isbn10To13(isbn10 string) string {
+	body := "978" + isbn10[:9]
+	sum := 0
+	for i, c := range body {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(c-'0')
+	}
+	return body + string(rune('0'+(10-sum%10)%10))
+}
+
+// isbn13To10 converts a 978-prefixed ISBN-13 back to its ISBN-10 form.
+// Other prefixes have no ISBN-10 equivalent and yield an empty string.
+func isbn13To10(isbn13 string) string {
+	if !strings.HasPrefix(isbn13, "978") {
+		return ""
+	}
+	body := isbn13[3:12]
+	sum := 0
+	for i, c := range body {
+		sum += (10 - i) * int(c-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X"
+	}
+	return body + string(rune('0'+check))
+}
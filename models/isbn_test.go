@@ -0,0 +1,91 @@
+package models
+
+import "testing"
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "0306406152", want: "9780306406157"},
+		{raw: "0-306-40615-2", want: "9780306406157"},
+		{raw: " 978 0 306 40615 7 ", want: "9780306406157"},
+		{raw: "080442957x", want: "9780804429573"},
+		{raw: "0306406153", wantErr: true},
+		{raw: "9780306406158", wantErr: true},
+		{raw: "X306406152", wantErr: true},
+		{raw: "97803064061", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeISBN(tt.raw)
+		if tt.wantErr {
+			if err != ErrInvalidISBN {
+				t.Errorf("NormalizeISBN(%q) error = %v, want ErrInvalidISBN", tt.raw, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeISBN(%q) = %q, %v, want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestISBN13To10(t *testing.T) {
+	tests := []struct {
+		isbn13 string
+		want   string
+	}{
+		{isbn13: "9780306406157", want: "0306406152"},
+		{isbn13: "9780804429573", want: "080442957X"},
+		{isbn13: "9791090636071", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := isbn13To10(tt.isbn13); got != tt.want {
+			t.Errorf("isbn13To10(%q) = %q, want %q", tt.isbn13, got, tt.want)
+		}
+	}
+}
+
+func TestBookValidateISBN(t *testing.T) {
+	tests := []struct {
+		name       string
+		isbn10     string
+		isbn13     string
+		wantISBN10 string
+		wantISBN13 string
+		wantError  string
+	}{
+		{name: "isbn10 only", isbn10: "0-306-40615-2", wantISBN10: "0306406152", wantISBN13: "9780306406157"},
+		{name: "isbn13 only", isbn13: "978-0-306-40615-7", wantISBN10: "0306406152", wantISBN13: "9780306406157"},
+		{name: "both matching", isbn10: "0306406152", isbn13: "9780306406157", wantISBN10: "0306406152", wantISBN13: "9780306406157"},
+		{name: "both different", isbn10: "0306406152", isbn13: "9780804429573", wantError: "isbn13"},
+		{name: "bad isbn10", isbn10: "0306406153", wantError: "isbn10"},
+		{name: "bad isbn13", isbn13: "9780306406158", wantError: "isbn13"},
+		{name: "neither"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := Book{ISBN10: tt.isbn10, ISBN13: tt.isbn13}
+			errors := map[string]string{}
+			book.validateISBN(errors)
+
+			if tt.wantError != "" {
+				if _, ok := errors[tt.wantError]; !ok {
+					t.Fatalf("errors = %v, want an error for %s", errors, tt.wantError)
+				}
+				return
+			}
+			if len(errors) > 0 {
+				t.Fatalf("unexpected errors %v", errors)
+			}
+			if book.ISBN10 != tt.wantISBN10 || book.ISBN13 != tt.wantISBN13 {
+				t.Errorf("got %q / %q, want %q / %q", book.ISBN10, book.ISBN13, tt.wantISBN10, tt.wantISBN13)
+			}
+		})
+	}
+}
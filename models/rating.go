@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// DefaultRatingDimensions are the sub-ratings offered for books whose
+// category has no list of its own.
+var DefaultRatingDimensions = []string{"plot", "characters", "writing", "pacing"}
+
+var (
+	ratingDimensions  = map[string][]string{}
+	dimensionNameExpr = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// SetRatingDimensions sets the sub-ratings offered per book category.
+// Categories are matched case-insensitively, and the "*" entry, if present,
+// replaces DefaultRatingDimensions. It is meant to be called once at
+// startup.
+func SetRatingDimensions(byCategory map[string][]string) error {
+	dimensions := map[string][]string{}
+	for category, names := range byCategory {
+		for _, name := range names {
+			if !dimensionNameExpr.MatchString(name) {
+				return fmt.Errorf("invalid rating dimension %q for category %q", name, category)
+			}
+		}
+		dimensions[strings.ToLower(strings.TrimSpace(category))] = names
+	}
+	ratingDimensions = dimensions
+	return nil
+}
+
+// RatingDimensionsFor returns the sub-ratings offered for books of the
+// given category.
+func RatingDimensionsFor(category string) []string {
+	if names, ok := ratingDimensions[strings.ToLower(strings.TrimSpace(category))]; ok {
+		return names
+	}
+	if names, ok := ratingDimensions["*"]; ok {
+		return names
+	}
+	return DefaultRatingDimensions
+}
+
+// DimensionRating is a book's aggregate for one sub-rating.
+type DimensionRating struct {
+	Avg   float64 `json:"avg" bson:"avg"`
+	Count int64   `json:"count" bson:"count"`
+	Sum   float64 `json:"-" bson:"sum"`
+}
+
+// ValidRating reports whether r is a star rating from 1 to 5 in half-star
+// steps.
+func ValidRating(r float64) bool {
+	return r >= 1 && r <= 5 && r*2 == math.Trunc(r*2)
+}
+
+// ValidateRatings checks an overall rating and its optional sub-ratings
+// against the dimensions offered for the book's category.
+func ValidateRatings(rating float64, ratings map[string]float64, category string) map[string]string {
+	errors := make(map[string]string)
+	if !ValidRating(rating) {
+		errors["rating"] = "Rating must be between 1 and 5 in steps of 0.5"
+	}
+
+	allowed := RatingDimensionsFor(category)
+	for name, value := range ratings {
+		field := "ratings." + name
+		switch {
+		case !contains(allowed, name):
+			errors[field] = fmt.Sprintf("Unknown rating dimension; expected one of %s", strings.Join(allowed, ", "))
+		case !ValidRating(value):
+			errors[field] = "Rating must be between 1 and 5 in steps of 0.5"
+		}
+	}
+	return errors
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportReasons are the reason codes a review can be reported for.
+var ReportReasons = map[string]string{
+	"spam":      "Spam or advertising",
+	"offensive": "Offensive or abusive language",
+	"spoilers":  "Unmarked spoilers",
+	"off_topic": "Not about the book",
+	"other":     "Something else",
+}
+
+const (
+	ReportOpen     = "open"
+	ReportResolved = "resolved"
+)
+
+// Moderation actions an admin can take on a reported review.
+const (
+	ModerationApprove = "approve"
+	ModerationHide    = "hide"
+	ModerationDelete  = "delete"
+)
+
+// Report is one user's complaint about a review. Each user can report a
+// review once.
+type Report struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ReviewID   primitive.ObjectID `json:"review_id" bson:"review_id"`
+	ReporterID primitive.ObjectID `json:"reporter_id" bson:"reporter_id"`
+	Reason     string             `json:"reason" bson:"reason"`
+	Details    string             `json:"details,omitempty" bson:"details,omitempty"`
+	Status     string             `json:"status" bson:"status"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	Resolution *Resolution        `json:"resolution,omitempty" bson:"resolution,omitempty"`
+}
+
+// Resolution records how an admin dealt with a report.
+type Resolution struct {
+	Action     string             `json:"action" bson:"action"`
+	Note       string             `json:"note,omitempty" bson:"note,omitempty"`
+	ResolvedBy primitive.ObjectID `json:"resolved_by" bson:"resolved_by"`
+	ResolvedAt time.Time          `json:"resolved_at" bson:"resolved_at"`
+}
+
+func (r *Report) Validate() map[string]string {
+	errors := make(map[string]string)
+	if _, ok := ReportReasons[r.Reason]; !ok {
+		errors["reason"] = "Unknown report reason"
+	}
+	if len(r.Details) > 1000 {
+		errors["details"] = "Details must be at most 1000 characters"
+	}
+	return errors
+}
@@ -1,19 +1,63 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Review statuses. Reviews without a status are published; reviews in any
+// of UnpublishedReviewStatuses are only shown to their author and admins.
+const (
+	ReviewPublished = "published"
+	ReviewHidden    = "hidden"
+	ReviewPending   = "pending"
+)
+
+var UnpublishedReviewStatuses = []string{ReviewHidden, ReviewPending}
+
 type Review struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Username  string             `json:"username" bson:"username"`
-	Review    string             `json:"review" bson:"review"`
-	Rating    int                `json:"rating" bson:"rating" binding:"required,min=1,max=5"`
-	CreatedAt primitive.DateTime `bson:"created_at" json:"created_at"`
-	UpdatedAt primitive.DateTime `bson:"updated_at" json:"updated_at"`
-	Book      Book               `json:"book" bson:"book"`
-	User      User               `json:"user" bson:"user"`
+	ID               primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID           primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Username         string              `json:"username" bson:"username"`
+	Review           string              `json:"review" bson:"review"`
+	ContainsSpoilers bool                `json:"contains_spoilers" bson:"contains_spoilers"`
+	Content          *ReviewContent      `json:"content,omitempty" bson:"-"`
+	Rating           float64             `json:"rating" bson:"rating" binding:"required,min=1,max=5"`
+	Ratings          map[string]float64  `json:"ratings,omitempty" bson:"ratings,omitempty"`
+	CreatedAt        primitive.DateTime  `bson:"created_at" json:"created_at"`
+	UpdatedAt        primitive.DateTime  `bson:"updated_at" json:"updated_at"`
+	EditedBy         string              `json:"edited_by,omitempty" bson:"edited_by,omitempty"`
+	Version          int64               `json:"version" bson:"version"`
+	Status           string              `json:"status,omitempty" bson:"status,omitempty"`
+	Flags            []string            `json:"flags,omitempty" bson:"flags,omitempty"`
+	TextHash         string              `json:"-" bson:"text_hash,omitempty"`
+	HelpfulCount     int64               `json:"helpful_count" bson:"helpful_count"`
+	UnhelpfulCount   int64               `json:"unhelpful_count" bson:"unhelpful_count"`
+	CommentCount     int64               `json:"comment_count" bson:"comment_count"`
+	DeletedAt        *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy        *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	Book             BookSummary         `json:"book" bson:"book"`
+}
+
+func (r Review) Published() bool {
+	for _, status := range UnpublishedReviewStatuses {
+		if r.Status == status {
+			return false
+		}
+	}
+	return true
+}
+
+// Render fills in Content from the review text. With redact set, Review is
+// replaced by the redacted text as well, so that clients which only read
+// Review never see a spoiler.
+func (r *Review) Render(redact bool) {
+	content := RenderContent(r.Review, r.ContainsSpoilers, redact)
+	r.Content = &content
+	if redact {
+		r.Review = content.Text
+	}
 }
 
 func (r *Review) Validate() map[string]string {
@@ -21,8 +65,8 @@ func (r *Review) Validate() map[string]string {
 	if r.Review == "" {
 		errors["review"] = "Review is required"
 	}
-	if r.Rating < 1 || r.Rating > 5 {
-		errors["rating"] = "Rating must be between 1 and 5"
+	for field, message := range ValidateRatings(r.Rating, r.Ratings, r.Book.Category) {
+		errors[field] = message
 	}
 	return errors
 }
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons recorded when a session is revoked.
+const (
+	SessionRevokedLogout         = "logout"
+	SessionRevokedTokenReuse     = "refresh_token_reused"
+	SessionRevokedPasswordReset  = "password_reset"
+	SessionRevokedPasswordChange = "password_change"
+	SessionRevokedLogoutAll      = "logout_all"
+	SessionRevokedBan            = "banned"
+	SessionRevokedByUser         = "revoked_by_user"
+	SessionRevokedByAdmin        = "revoked_by_admin"
+)
+
+// Session is one sign-in of a user. Its refresh token is replaced every time
+// it is used, and RefreshJTI holds the ID of the only one that may be used
+// next; presenting any older token revokes the session.
+type Session struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id"`
+	UserID        primitive.ObjectID `json:"user_id" bson:"user_id"`
+	RefreshJTI    string             `json:"-" bson:"refresh_jti"`
+	PreviousJTI   string             `json:"-" bson:"previous_jti,omitempty"`
+	RotatedAt     *time.Time         `json:"-" bson:"rotated_at,omitempty"`
+	UserAgent     string             `json:"user_agent" bson:"user_agent,omitempty"`
+	IP            string             `json:"ip" bson:"ip,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	LastSeenAt    time.Time          `json:"last_seen_at" bson:"last_seen_at"`
+	ExpiresAt     time.Time          `json:"expires_at" bson:"expires_at"`
+	RevokedAt     *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	RevokedReason string             `json:"revoked_reason,omitempty" bson:"revoked_reason,omitempty"`
+
+	// Current marks the session making the request in session listings.
+	Current bool `json:"current" bson:"-"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SpoilerMarker opens and closes a spoiler span in review text, as in
+// "The butler ||did it||."
+const SpoilerMarker = "||"
+
+// SpoilerPlaceholder replaces each spoiler span in redacted text.
+const SpoilerPlaceholder = "[spoiler]"
+
+// TextSegment is a run of review text that is either all spoiler or none.
+type TextSegment struct {
+	Text    string `json:"text"`
+	Spoiler bool   `json:"spoiler,omitempty"`
+}
+
+// TextSpan locates a spoiler in ReviewContent.Text. Offsets count Unicode
+// code points, end exclusive.
+type TextSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ReviewContent is the parsed form of a review's text. Text has the markup
+// removed and, when Redacted, each spoiler replaced by SpoilerPlaceholder;
+// Spoilers gives the position of each spoiler, or placeholder, in Text.
+type ReviewContent struct {
+	Text     string        `json:"text"`
+	Segments []TextSegment `json:"segments"`
+	Spoilers []TextSpan    `json:"spoilers"`
+	Redacted bool          `json:"redacted"`
+}
+
+// ParseSpoilers splits text on SpoilerMarker pairs. A marker without a
+// partner is kept as ordinary text, and empty spoilers are dropped.
+func ParseSpoilers(text string) []TextSegment {
+	segments := []TextSegment{}
+	add := func(s string, spoiler bool) {
+		if s == "" {
+			return
+		}
+		if n := len(segments); n > 0 && segments[n-1].Spoiler == spoiler {
+			segments[n-1].Text += s
+			return
+		}
+		segments = append(segments, TextSegment{Text: s, Spoiler: spoiler})
+	}
+
+	for {
+		start := strings.Index(text, SpoilerMarker)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(text[start+len(SpoilerMarker):], SpoilerMarker)
+		if end < 0 {
+			break
+		}
+		end += start + len(SpoilerMarker)
+
+		add(text[:start], false)
+		add(text[start+len(SpoilerMarker):end], true)
+		text = text[end+len(SpoilerMarker):]
+	}
+	add(text, false)
+	return segments
+}
+
+// RenderContent builds the parsed content of text. With redact set, spoiler
+// segments are emptied and replaced by SpoilerPlaceholder in Text; a review
+// marked as containing spoilers is redacted as a whole.
+func RenderContent(text string, containsSpoilers, redact bool) ReviewContent {
+	segments := ParseSpoilers(text)
+	if redact && containsSpoilers && text != "" {
+		segments = []TextSegment{{Text: text, Spoiler: true}}
+	}
+
+	content := ReviewContent{Segments: segments, Spoilers: []TextSpan{}, Redacted: redact}
+	var b strings.Builder
+	offset := 0
+	for i, segment := range segments {
+		if segment.Spoiler && redact {
+			content.Segments[i].Text = ""
+			segment.Text = SpoilerPlaceholder
+		}
+		length := utf8.RuneCountInString(segment.Text)
+		if segment.Spoiler {
+			content.Spoilers = append(content.Spoilers, TextSpan{Start: offset, End: offset + length})
+		}
+		b.WriteString(segment.Text)
+		offset += length
+	}
+	content.Text = b.String()
+	return content
+}
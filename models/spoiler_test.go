@@ -0,0 +1,95 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSpoilers(t *testing.T) {
+	tests := []struct {
+		text string
+		want []TextSegment
+	}{
+		{text: "", want: []TextSegment{}},
+		{text: "No spoilers here.", want: []TextSegment{{Text: "No spoilers here."}}},
+		{
+			text: "The butler ||did it||.",
+			want: []TextSegment{{Text: "The butler "}, {Text: "did it", Spoiler: true}, {Text: "."}},
+		},
+		{text: "a || b", want: []TextSegment{{Text: "a || b"}}},
+		{
+			text: "a ||b|| ||c",
+			want: []TextSegment{{Text: "a "}, {Text: "b", Spoiler: true}, {Text: " ||c"}},
+		},
+		{text: "||||x", want: []TextSegment{{Text: "x"}}},
+		{text: "||a||||b||", want: []TextSegment{{Text: "ab", Spoiler: true}}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseSpoilers(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSpoilers(%q) = %#v, want %#v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRenderContent(t *testing.T) {
+	tests := []struct {
+		name             string
+		text             string
+		containsSpoilers bool
+		redact           bool
+		want             ReviewContent
+	}{
+		{
+			name: "shown",
+			text: "Héllo ||wörld||",
+			want: ReviewContent{
+				Text:     "Héllo wörld",
+				Segments: []TextSegment{{Text: "Héllo "}, {Text: "wörld", Spoiler: true}},
+				Spoilers: []TextSpan{{Start: 6, End: 11}},
+			},
+		},
+		{
+			name:   "redacted",
+			text:   "Héllo ||wörld||",
+			redact: true,
+			want: ReviewContent{
+				Text:     "Héllo [spoiler]",
+				Segments: []TextSegment{{Text: "Héllo "}, {Text: "", Spoiler: true}},
+				Spoilers: []TextSpan{{Start: 6, End: 15}},
+				Redacted: true,
+			},
+		},
+		{
+			name:             "whole review redacted",
+			text:             "It was all a dream.",
+			containsSpoilers: true,
+			redact:           true,
+			want: ReviewContent{
+				Text:     "[spoiler]",
+				Segments: []TextSegment{{Text: "", Spoiler: true}},
+				Spoilers: []TextSpan{{Start: 0, End: 9}},
+				Redacted: true,
+			},
+		},
+		{
+			name:             "whole review shown",
+			text:             "It was all a dream.",
+			containsSpoilers: true,
+			want: ReviewContent{
+				Text:     "It was all a dream.",
+				Segments: []TextSegment{{Text: "It was all a dream."}},
+				Spoilers: []TextSpan{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderContent(tt.text, tt.containsSpoilers, tt.redact)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RenderContent() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
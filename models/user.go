@@ -19,36 +19,56 @@ type User struct {
 	IsAdmin   bool               `json:"is_admin" bson:"is_admin"`
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+
+	// EmailVerified is set once the user follows the link sent to their
+	// email address.
+	EmailVerified      bool       `json:"email_verified" bson:"email_verified"`
+	VerificationSentAt *time.Time `json:"-" bson:"verification_sent_at,omitempty"`
+
+	// BannedAt is set while an admin has banned the user, who can then
+	// neither log in nor use any token issued earlier.
+	BannedAt  *time.Time `json:"banned_at,omitempty" bson:"banned_at,omitempty"`
+	BanReason string     `json:"ban_reason,omitempty" bson:"ban_reason,omitempty"`
+
+	// PasswordResetHash is the SHA-256 of the outstanding password reset
+	// token; the token itself is only ever sent to the user.
+	PasswordResetHash      string     `json:"-" bson:"password_reset_hash,omitempty"`
+	PasswordResetExpiresAt *time.Time `json:"-" bson:"password_reset_expires_at,omitempty"`
+	// TokensValidAfter revokes every token issued before it.
+	TokensValidAfter *time.Time `json:"-" bson:"tokens_valid_after,omitempty"`
+}
+
+// PublicUser is the part of a user that may be shown to anyone. Use it
+// instead of User in responses and in snapshots stored on other documents.
+type PublicUser struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
+	Username string             `json:"username" bson:"username"`
+}
+
+func (u User) Public() PublicUser {
+	return PublicUser{ID: u.ID, Username: u.Username}
 }
 
 func (u *User) Validate(ctx context.Context, db *mongo.Collection) map[string]string {
 	errors := make(map[string]string)
 
-	if u.Username == "" {
-		errors["username"] = "Username is required"
+	u.Username = NormalizeUsername(u.Username)
+	if message := ValidateUsername(u.Username); message != "" {
+		errors["username"] = message
 	} else {
-		u.Username = strings.ToLower(strings.TrimSpace(u.Username))
-		if len(u.Username) < 3 || len(u.Username) > 100 {
-			errors["username"] = "Username must be between 3 and 100 characters"
-		}
-		matched, _ := regexp.MatchString(`^[a-zA-Z0-9_.-]+$`, u.Username)
-		if !matched {
-			errors["username"] = "Username can only contain letters, numbers, underscores, dashes, and periods"
-		} else {
-			// Check if username exists in the database
-			filter := bson.M{"username": u.Username}
-			var existingUser User
-			err := db.FindOne(ctx, filter).Decode(&existingUser)
-			if err == nil {
-				errors["username"] = "Username already exists"
-			}
+		// Check if username exists in the database
+		filter := bson.M{"username": u.Username}
+		var existingUser User
+		err := db.FindOne(ctx, filter).Decode(&existingUser)
+		if err == nil {
+			errors["username"] = "Username already exists"
 		}
 	}
 
 	if u.Email == "" {
 		errors["email"] = "Email is required"
 	} else {
-		u.Email = strings.ToLower(strings.TrimSpace(u.Email))
+		u.Email = NormalizeEmail(u.Email)
 		emailRegex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
 		matched, _ := regexp.MatchString(emailRegex, u.Email)
 		if !matched {
@@ -64,17 +84,50 @@ func (u *User) Validate(ctx context.Context, db *mongo.Collection) map[string]st
 		}
 	}
 
-	if u.Password == "" {
-		errors["password"] = "Password is required"
-	} else {
-		if len(u.Password) < 6 {
-			errors["password"] = "Password must be at least 6 characters long"
-		}
-		passwordRegex := `^(?=.*[a-z])(?=.*[A-Z])(?=.*\d).+$`
-		matched, _ := regexp.MatchString(passwordRegex, u.Password)
-		if !matched {
-			errors["password"] = "Password must contain at least one lowercase letter, one uppercase letter, and one number"
-		}
+	if message := ValidatePassword(u.Password); message != "" {
+		errors["password"] = message
 	}
 	return errors
 }
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// NormalizeUsername returns the form in which usernames are stored.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// ValidateUsername returns what is wrong with a normalized username, or ""
+// if it is acceptable. It does not check whether the name is taken.
+func ValidateUsername(username string) string {
+	switch {
+	case username == "":
+		return "Username is required"
+	case len(username) < 3 || len(username) > 100:
+		return "Username must be between 3 and 100 characters"
+	case !usernamePattern.MatchString(username):
+		return "Username can only contain letters, numbers, underscores, dashes, and periods"
+	}
+	return ""
+}
+
+// NormalizeEmail returns the form in which email addresses are stored.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidatePassword returns what is wrong with a new password, or "" if it is
+// acceptable.
+func ValidatePassword(password string) string {
+	switch {
+	case password == "":
+		return "Password is required"
+	case len(password) < 6:
+		return "Password must be at least 6 characters long"
+	case !strings.ContainsAny(password, "abcdefghijklmnopqrstuvwxyz"),
+		!strings.ContainsAny(password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ"),
+		!strings.ContainsAny(password, "0123456789"):
+		return "Password must contain at least one lowercase letter, one uppercase letter, and one number"
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReviewVote is one user's helpful or not helpful vote on a review.
+type ReviewVote struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ReviewID  primitive.ObjectID `json:"review_id" bson:"review_id"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Helpful   bool               `json:"helpful" bson:"helpful"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// VoteCounter is the review field that counts votes like this one.
+func (v ReviewVote) VoteCounter() string {
+	if v.Helpful {
+		return "helpful_count"
+	}
+	return "unhelpful_count"
+}
@@ -0,0 +1,41 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps revoked token IDs in memory. Revocations are lost on
+// restart and are not shared between servers, so it is only suitable for
+// tests and single-process development setups.
+type MemoryStore struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{revoked: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, expiry := range s.revoked {
+		if !expiry.After(now) {
+			delete(s.revoked, id)
+		}
+	}
+	s.revoked[tokenID] = expiresAt
+	return nil
+}
+
+func (s *MemoryStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiry, ok := s.revoked[tokenID]
+	return ok && expiry.After(time.Now()), nil
+}
@@ -0,0 +1,45 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if err := store.Revoke(ctx, "live", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Revoke(ctx, "expired", time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tokenID string
+		want    bool
+	}{
+		{tokenID: "live", want: true},
+		{tokenID: "expired", want: false},
+		{tokenID: "unknown", want: false},
+	}
+	for _, tt := range tests {
+		got, err := store.IsRevoked(ctx, tt.tokenID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("IsRevoked(%q) = %v, want %v", tt.tokenID, got, tt.want)
+		}
+	}
+
+	// Revoking another token drops the ones that have expired.
+	if err := store.Revoke(ctx, "other", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.revoked["expired"]; ok {
+		t.Error("expired revocation was not pruned")
+	}
+}
@@ -0,0 +1,36 @@
+package revocation
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps revoked token IDs in a collection. A TTL index on
+// expires_at, created by database.EnsureIndexes, clears them out once the
+// tokens have expired.
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection}
+}
+
+func (s *MongoStore) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	_, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": tokenID},
+		bson.M{"$set": bson.M{"expires_at": expiresAt, "revoked_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (s *MongoStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	count, err := s.collection.CountDocuments(ctx, bson.M{"_id": tokenID}, options.Count().SetLimit(1))
+	return count > 0, err
+}
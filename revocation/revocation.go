@@ -0,0 +1,13 @@
+package revocation
+
+import (
+	"context"
+	"time"
+)
+
+// Store remembers revoked tokens by their ID (the jti claim) until the
+// tokens would have expired anyway.
+type Store interface {
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"spa_media_review/controllers"
+	"spa_media_review/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAdminRoutes(router *gin.Engine, ac *controllers.AdminController) {
+	adminRoutes := router.Group("/api/admin")
+	adminRoutes.Use(middleware.AuthMiddleware(), middleware.RequireAdmin())
+	{
+		adminRoutes.GET("/trash", ac.GetTrash)
+		adminRoutes.POST("/users/:id/ban", ac.BanUser)
+		adminRoutes.DELETE("/users/:id/ban", ac.UnbanUser)
+		adminRoutes.GET("/users/:id/sessions", ac.GetUserSessions)
+		adminRoutes.DELETE("/users/:id/sessions/:sessionId", ac.RevokeUserSession)
+	}
+}
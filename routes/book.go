@@ -13,7 +13,9 @@ func RegisterBookRoutes(router *gin.Engine, bc *controllers.BookController) {
 	{
 		bookRoutes.GET("/", bc.GetBooks)
 		bookRoutes.GET("/search", bc.SearchBooks)
+		bookRoutes.GET("/isbn/:isbn", bc.GetBookByISBN)
 		bookRoutes.GET("/:id", bc.GetBookByID)
+		bookRoutes.GET("/:id/cover", bc.GetBookCover)
 	}
 
 	adminRoutes := router.Group("/api/books")
@@ -23,7 +25,9 @@ func RegisterBookRoutes(router *gin.Engine, bc *controllers.BookController) {
 		adminRoutes.GET("/new", bc.NewBook)
 		adminRoutes.GET("/edit/:id", bc.UpdateBook)
 		adminRoutes.PUT("/edit/:id", bc.EditedBook)
+		adminRoutes.PATCH("/:id", bc.PatchBook)
 		adminRoutes.GET("/delete/:id", bc.DeleteBookConfirmation)
 		adminRoutes.DELETE("/delete/:id", bc.DeleteBook)
+		adminRoutes.POST("/:id/restore", bc.RestoreBook)
 	}
 }
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"spa_media_review/controllers"
+	"spa_media_review/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCommentRoutes(router *gin.Engine, cc *controllers.CommentController) {
+	router.GET("/api/reviews/:id/comments", cc.GetComments)
+
+	protected := router.Group("/api")
+	protected.Use(middleware.AuthMiddleware())
+	{
+		protected.POST("/reviews/:id/comments", cc.CreateComment)
+	}
+
+	ownerRoutes := router.Group("/api/comments")
+	ownerRoutes.Use(middleware.AuthMiddleware(), middleware.RequireOwnerOrAdmin(cc.CommentOwner))
+	{
+		ownerRoutes.PUT("/:id", cc.EditComment)
+		ownerRoutes.DELETE("/:id", cc.DeleteComment)
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"spa_media_review/controllers"
+	"spa_media_review/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterModerationRoutes(router *gin.Engine, mc *controllers.ModerationController) {
+	protected := router.Group("/api/reviews")
+	protected.Use(middleware.AuthMiddleware())
+	{
+		protected.POST("/:id/report", mc.ReportReview)
+	}
+
+	adminRoutes := router.Group("/api/admin/moderation")
+	adminRoutes.Use(middleware.AuthMiddleware(), middleware.RequireAdmin())
+	{
+		adminRoutes.GET("", mc.GetModerationQueue)
+		adminRoutes.GET("/pending", mc.GetPendingReviews)
+		adminRoutes.POST("/:id/resolve", mc.ResolveReports)
+	}
+}
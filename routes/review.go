@@ -19,13 +19,21 @@ func RegisterReviewRoutes(router *gin.Engine, rc *controllers.ReviewController)
 	{
 		protected.POST("/", rc.CreateReview)
 		protected.GET("/new/:bookId", rc.NewReview)
+		protected.GET("/book/:bookId/mine", rc.GetMyReview)
+		protected.POST("/:id/vote", rc.VoteReview)
+		protected.DELETE("/:id/vote", rc.DeleteVote)
+	}
+	ownerRoutes := router.Group("/api/reviews")
+	ownerRoutes.Use(middleware.AuthMiddleware(), middleware.RequireOwnerOrAdmin(rc.ReviewOwner))
+	{
+		ownerRoutes.GET("/edit/:id", rc.UpdateReview)
+		ownerRoutes.PUT("/edit/:id", rc.EditedReview)
+		ownerRoutes.GET("/delete/:id", rc.DeleteReviewConfirmation)
+		ownerRoutes.DELETE("/delete/:id", rc.DeleteReview)
 	}
 	adminRoutes := router.Group("/api/reviews")
 	adminRoutes.Use(middleware.AuthMiddleware(), middleware.RequireAdmin())
 	{
-		adminRoutes.GET("/edit/:id", rc.UpdateReview)
-		adminRoutes.PUT("/edit/:id", rc.EditedReview)
-		adminRoutes.GET("/delete/:id", rc.DeleteReviewConfirmation)
-		adminRoutes.DELETE("/delete/:id", rc.DeleteReview)
+		adminRoutes.POST("/:id/restore", rc.RestoreReview)
 	}
 }
@@ -16,11 +16,20 @@ func RegisterUserRoutes(router *gin.Engine, uc *controllers.UserController) {
 		userRoutes.POST("/login", uc.LoginUser)
 		userRoutes.GET("/forgot_password", uc.ForgotPassword)
 		userRoutes.POST("/forgot_password", uc.ResetPassword)
+		userRoutes.POST("/reset_password/confirm", uc.ConfirmPasswordReset)
+		userRoutes.GET("/verify", uc.VerifyEmail)
+		userRoutes.POST("/refresh", uc.RefreshTokens)
 	}
 
 	protected := router.Group("/api/users")
 	protected.Use(middleware.AuthMiddleware())
 	{
 		protected.POST("/logout", uc.LogoutUser)
+		protected.POST("/logout_all", uc.LogoutAll)
+		protected.PATCH("/me", uc.UpdateProfile)
+		protected.PUT("/me/password", uc.ChangePassword)
+		protected.GET("/sessions", uc.GetSessions)
+		protected.DELETE("/sessions/:id", uc.RevokeSession)
+		protected.POST("/verify/resend", uc.ResendVerification)
 	}
 }
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GridFSStore keeps blobs in a MongoDB GridFS bucket, using the blob key as
+// the GridFS filename.
+type GridFSStore struct {
+	bucket *gridfs.Bucket
+}
+
+type gridfsFile struct {
+	ID         interface{} `bson:"_id"`
+	Length     int64       `bson:"length"`
+	UploadDate time.Time   `bson:"uploadDate"`
+	Metadata   struct {
+		ContentType string `bson:"content_type"`
+		ETag        string `bson:"etag"`
+	} `bson:"metadata"`
+}
+
+func NewGridFSStore(db *mongo.Database, bucketName string) (*GridFSStore, error) {
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName(bucketName))
+	if err != nil {
+		return nil, err
+	}
+	return &GridFSStore{bucket: bucket}, nil
+}
+
+func (s *GridFSStore) Put(ctx context.Context, key, contentType string, data []byte) (BlobInfo, error) {
+	existing, err := s.find(ctx, key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	etag := computeETag(data)
+	uploadOpts := options.GridFSUpload().SetMetadata(bson.M{
+		"content_type": contentType,
+		"etag":         etag,
+	})
+	if _, err := s.bucket.UploadFromStream(key, bytes.NewReader(data), uploadOpts); err != nil {
+		return BlobInfo{}, err
+	}
+
+	// Old revisions are removed only after the new one is in place so that a
+	// failed upload never leaves the key empty.
+	for _, file := range existing {
+		if err := s.bucket.DeleteContext(ctx, file.ID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			return BlobInfo{}, err
+		}
+	}
+
+	return BlobInfo{
+		Key:         key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		ETag:        etag,
+		ModTime:     time.Now(),
+	}, nil
+}
+
+func (s *GridFSStore) Get(ctx context.Context, key string) (io.ReadCloser, BlobInfo, error) {
+	files, err := s.find(ctx, key)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+	if len(files) == 0 {
+		return nil, BlobInfo{}, ErrNotFound
+	}
+	file := files[len(files)-1]
+
+	stream, err := s.bucket.OpenDownloadStream(file.ID)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, BlobInfo{}, ErrNotFound
+	} else if err != nil {
+		return nil, BlobInfo{}, err
+	}
+
+	return stream, BlobInfo{
+		Key:         key,
+		ContentType: file.Metadata.ContentType,
+		Size:        file.Length,
+		ETag:        file.Metadata.ETag,
+		ModTime:     file.UploadDate,
+	}, nil
+}
+
+func (s *GridFSStore) Delete(ctx context.Context, key string) error {
+	files, err := s.find(ctx, key)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := s.bucket.DeleteContext(ctx, file.ID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// find returns every revision stored under key, oldest first.
+func (s *GridFSStore) find(ctx context.Context, key string) ([]gridfsFile, error) {
+	cursor, err := s.bucket.FindContext(ctx, bson.M{"filename": key},
+		options.GridFSFind().SetSort(bson.D{{Key: "uploadDate", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var files []gridfsFile
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStore writes blobs to a directory on disk. Each blob has a JSON
+// sidecar file holding its content type and ETag.
+type LocalStore struct {
+	root string
+}
+
+type localMeta struct {
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag"`
+}
+
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.HasSuffix(clean, ".meta") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key, contentType string, data []byte) (BlobInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return BlobInfo{}, err
+	}
+
+	meta := localMeta{ContentType: contentType, ETag: computeETag(data)}
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return BlobInfo{}, err
+	}
+	if err := writeFileAtomic(path+".meta", metaBytes); err != nil {
+		return BlobInfo{}, err
+	}
+
+	return BlobInfo{
+		Key:         key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		ETag:        meta.ETag,
+		ModTime:     time.Now(),
+	}, nil
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, BlobInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+
+	metaBytes, err := os.ReadFile(path + ".meta")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, BlobInfo{}, ErrNotFound
+	} else if err != nil {
+		return nil, BlobInfo{}, err
+	}
+	var meta localMeta
+	if err := json.Unmarshal(metaBytes, &meta); err != nil {
+		return nil, BlobInfo{}, fmt.Errorf("corrupt metadata for blob %q: %v", key, err)
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, BlobInfo{}, ErrNotFound
+	} else if err != nil {
+		return nil, BlobInfo{}, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, BlobInfo{}, err
+	}
+
+	return file, BlobInfo{
+		Key:         key,
+		ContentType: meta.ContentType,
+		Size:        stat.Size(),
+		ETag:        meta.ETag,
+		ModTime:     stat.ModTime(),
+	}, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{path, path + ".meta"} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
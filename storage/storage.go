@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+type BlobInfo struct {
+	Key         string
+	ContentType string
+	Size        int64
+	ETag        string
+	ModTime     time.Time
+}
+
+// BlobStore keeps binary objects, such as book covers, outside of the
+// documents that reference them. Put overwrites any existing blob stored
+// under the same key.
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) (BlobInfo, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func computeETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}